@@ -0,0 +1,85 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// bindArray PostgreSQL 中将 slice 参数转换为数组, []byte 和 driver.Valuer 除外
+//
+//	sqlSession.Select("*").From("user").Where("id = ANY(#{ids})", []int64{1, 2, 3})
+func bindArray(arg any) any {
+	if arg == nil {
+		return nil
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return arg
+	}
+	if t := reflect.TypeOf(arg); t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		return pq.Array(arg)
+	}
+	return arg
+}
+
+// isArrayType 是否为可以映射 PostgreSQL 数组列的 slice 类型
+func isArrayType(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice || reflect.PointerTo(typ).Implements(scannerType) {
+		return false
+	}
+	_, ok := arrayScanType(typ.Elem())
+	return ok
+}
+
+// arrayScanType 返回元素类型为 elem 的 slice 映射数组列时使用的 pq.Array 支持的类型
+func arrayScanType(elem reflect.Type) (reflect.Type, bool) {
+	if reflect.PointerTo(elem).Implements(scannerType) {
+		return nil, true
+	}
+	switch elem.Kind() {
+	case reflect.Bool:
+		return reflect.TypeOf([]bool(nil)), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return reflect.TypeOf([]int64(nil)), elem.Kind() != reflect.Uint8
+	case reflect.Float32, reflect.Float64:
+		return reflect.TypeOf([]float64(nil)), true
+	case reflect.String:
+		return reflect.TypeOf([]string(nil)), true
+	case reflect.Slice:
+		return reflect.TypeOf([][]byte(nil)), elem.Elem().Kind() == reflect.Uint8
+	}
+	return nil, false
+}
+
+// arrayConverter 返回将 PostgreSQL 数组列转换为 typ 类型 slice 的 Converter
+func arrayConverter(typ reflect.Type) *Converter {
+	scanType, _ := arrayScanType(typ.Elem())
+	return &Converter{DbToGo: func(src any) (any, error) {
+		if src == nil {
+			return nil, nil
+		}
+		if scanType == nil {
+			// 元素实现了 sql.Scanner
+			value := reflect.New(typ)
+			if err := (pq.GenericArray{A: value.Interface()}).Scan(src); err != nil {
+				return nil, err
+			}
+			return value.Elem().Interface(), nil
+		}
+		scanned := reflect.New(scanType)
+		if err := pq.Array(scanned.Interface()).Scan(src); err != nil {
+			return nil, err
+		}
+		if scanType == typ {
+			return scanned.Elem().Interface(), nil
+		}
+		n := scanned.Elem().Len()
+		value := reflect.MakeSlice(typ, n, n)
+		for i := 0; i < n; i++ {
+			value.Index(i).Set(scanned.Elem().Index(i).Convert(typ.Elem()))
+		}
+		return value.Interface(), nil
+	}}
+}
@@ -0,0 +1,60 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+type arrayRow struct {
+	ID     int64
+	Scores []int
+	Tags   []string
+	Flags  []bool
+	Data   []byte
+}
+
+func TestPGArrayBind(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id"})
+	var rows []arrayRow
+	err := NewPostgreSqlSession(NewTxSession(db, false)).Select("id").From("t").
+		Where("id = ANY(#{ids})", []int64{1, 2}).Where("tags && #{tags}", []string{"a", "b c"}).
+		Where("data = #{data}", []byte("x")).AsList(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := fake.executed()[0].args
+	if args[0] != "{1,2}" || args[1] != `{"a","b c"}` || string(args[2].([]byte)) != "x" {
+		t.Errorf("args = %v", args)
+	}
+
+	// MySQL 不转换 slice
+	_, args, _ = NewMySqlSession(NewTxSession(db, false)).Select("id").From("t").Where("tags = #{tags}", []string{"a"}).Build()
+	if _, ok := args[0].([]string); !ok {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestPGArrayScan(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "scores", "tags", "flags", "data"},
+		[]driver.Value{int64(1), []byte("{90,85}"), []byte(`{a,"b c"}`), []byte("{t,f}"), []byte("raw")},
+		[]driver.Value{int64(2), nil, []byte("{}"), nil, nil})
+	var rows []arrayRow
+	if err := NewPostgreSqlSession(NewTxSession(db, false)).Select("*").From("t").AsList(&rows); err != nil {
+		t.Fatal(err)
+	}
+	r := rows[0]
+	if len(r.Scores) != 2 || r.Scores[1] != 85 || r.Tags[1] != "b c" || !r.Flags[0] || string(r.Data) != "raw" {
+		t.Errorf("rows[0] = %+v", r)
+	}
+	if r := rows[1]; r.Scores != nil || r.Tags == nil || len(r.Tags) != 0 {
+		t.Errorf("rows[1] = %+v", r)
+	}
+
+	fake.addResult([]string{"scores"}, []driver.Value{[]byte("{x}")})
+	var row arrayRow
+	if err := NewPostgreSqlSession(NewTxSession(db, false)).Select("scores").From("t").AsSingle(&row); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,258 @@
+package trysql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// assembler 将一对多 JOIN 查询的多条记录组装为带有集合字段的 struct。
+//
+// struct 通过 colname 的 pk 选项标识记录, 主键相同的记录合并为一个 struct,
+// 元素为 struct 的 slice 字段从带有前缀的列中填充, 前缀默认为 "字段列名__"。
+// 集合元素没有主键时, 只合并多个集合字段 JOIN 产生的重复元素,
+// 整条记录都相同的多条记录中的元素作为不同的元素保留。
+//
+//	type Order struct {
+//		ID    int64       `colname:"id,pk"`
+//		Items []OrderItem `colname:"items"`
+//	}
+//	type OrderItem struct {
+//		ID  int64 `colname:"id,pk"`
+//		Sku string
+//	}
+//	SELECT o.id, i.id AS items__id, i.sku AS items__sku FROM orders o LEFT JOIN order_items i ON i.order_id = o.id
+type assembler struct {
+	typ      reflect.Type
+	plan     *scanPlan
+	pks      []*fieldMeta
+	children []*assemblyChild
+}
+
+type assemblyChild struct {
+	coll *collectionMeta
+	*assembler
+}
+
+// needAssembly struct 是否需要组装: 同时存在主键和集合字段
+func needAssembly(meta *structMeta) bool {
+	return len(meta.pks) > 0 && len(meta.collections) > 0
+}
+
+// assemblers 缓存最近使用的 *assembler, 与 scanPlans 相同, 超出容量时被淘汰
+var assemblers = newScanPlanCache[*assembler](maxScanPlans)
+
+// getAssembler 返回 typ 对应 columns 的 *assembler
+func getAssembler(typ reflect.Type, columns []string, opts scanOptions) *assembler {
+	key := scanPlanKey{typ: typ, columns: strings.Join(columns, "\x00"), opts: opts}
+	if a, ok := assemblers.load(key); ok {
+		return a
+	}
+	return assemblers.loadOrStore(key, newAssembler(typ, columns, false, opts))
+}
+
+// newAssembler nullable 为 true 时, 列全部为 NULL 的记录被忽略, 用于 LEFT JOIN 的集合元素
+func newAssembler(typ reflect.Type, columns []string, nullable bool, opts scanOptions) *assembler {
+	meta := getStructMeta(typ)
+	a := &assembler{typ: typ, plan: newScanPlan(meta, columns, nullable, opts), pks: meta.pks}
+	for _, coll := range meta.collections {
+		childColumns := make([]string, len(columns))
+		for i, column := range columns {
+			if strings.HasPrefix(column, coll.prefix) {
+				childColumns[i] = column[len(coll.prefix):]
+			}
+		}
+		a.children = append(a.children, &assemblyChild{coll: coll, assembler: newAssembler(coll.elem, childColumns, true, opts)})
+	}
+	return a
+}
+
+// assemblyRow 一条记录中属于某个 struct 的部分
+type assemblyRow struct {
+	ptr      reflect.Value
+	scanDest []any
+	present  bool
+	children []*assemblyRow
+}
+
+func (a *assembler) newRow() *assemblyRow {
+	ptr := reflect.New(a.typ)
+	row := &assemblyRow{ptr: ptr, scanDest: a.plan.scanDest(ptr.Elem())}
+	for _, c := range a.children {
+		row.children = append(row.children, c.newRow())
+	}
+	return row
+}
+
+// fill 将 row 中映射的 rows.Scan 参数填充到 scanDest
+func (a *assembler) fill(row *assemblyRow, scanDest []any) {
+	for i, f := range a.plan.fields {
+		if f != nil {
+			scanDest[i] = row.scanDest[i]
+		}
+	}
+	for k, c := range a.children {
+		c.fill(row.children[k], scanDest)
+	}
+}
+
+func (a *assembler) assign(row *assemblyRow) error {
+	assigned, err := a.plan.assign(row.ptr.Elem(), row.scanDest)
+	if err != nil {
+		return err
+	}
+	row.present = assigned || !a.plan.nullable
+	for k, c := range a.children {
+		if err := c.assign(row.children[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assemblyNode 组装中的 struct
+type assemblyNode struct {
+	ptr   reflect.Value
+	lists []*assemblyList
+}
+
+// assemblyList 组装中的 struct 集合
+type assemblyList struct {
+	nodes []*assemblyNode
+	// index 标识相同的 struct 在 nodes 中的位置, 有主键时只有一个
+	index map[string][]int
+	// occurrences 没有主键时, 元素标识与整条记录相同的记录数
+	occurrences map[string]int
+}
+
+func newAssemblyList() *assemblyList {
+	return &assemblyList{index: map[string][]int{}, occurrences: map[string]int{}}
+}
+
+// merge 将 row 合并到 list 中主键相同的 struct, 不存在时追加。
+//
+// 没有主键时, 元素与整条记录 rowKey 都相同的第 n 条记录合并到第 n 个标识相同的 struct:
+// 其他集合字段 JOIN 产生的重复元素只保留一个, 完全相同的记录中的元素各保留一个
+func (a *assembler) merge(list *assemblyList, row *assemblyRow, rowKey string) {
+	key := a.key(row.ptr.Elem())
+	n := 1
+	if len(a.pks) == 0 {
+		occurrence := key + "\x02" + rowKey
+		list.occurrences[occurrence]++
+		n = list.occurrences[occurrence]
+	}
+	var node *assemblyNode
+	if indexes := list.index[key]; len(indexes) >= n {
+		node = list.nodes[indexes[n-1]]
+	} else {
+		node = &assemblyNode{ptr: row.ptr}
+		for range a.children {
+			node.lists = append(node.lists, newAssemblyList())
+		}
+		list.index[key] = append(indexes, len(list.nodes))
+		list.nodes = append(list.nodes, node)
+	}
+	for k, c := range a.children {
+		if child := row.children[k]; child.present {
+			c.merge(node.lists[k], child, rowKey)
+		}
+	}
+}
+
+// key 返回主键值组成的标识, 没有主键时使用所有映射字段的值
+func (a *assembler) key(value reflect.Value) string {
+	b := strings.Builder{}
+	if len(a.pks) > 0 {
+		for _, f := range a.pks {
+			writeKeyValue(&b, value, f)
+		}
+		return b.String()
+	}
+	a.writeFields(&b, value)
+	return b.String()
+}
+
+func (a *assembler) writeFields(b *strings.Builder, value reflect.Value) {
+	for _, f := range a.plan.fields {
+		if f != nil {
+			writeKeyValue(b, value, f)
+		}
+	}
+}
+
+// writeRowKey 将 row 及其所有集合元素映射字段的值写入整条记录的标识
+func (a *assembler) writeRowKey(b *strings.Builder, row *assemblyRow) {
+	a.writeFields(b, row.ptr.Elem())
+	for k, c := range a.children {
+		if child := row.children[k]; child.present {
+			b.WriteString("\x03")
+			c.writeRowKey(b, child)
+		} else {
+			b.WriteString("\x04")
+		}
+	}
+}
+
+// writeKeyValue 将 value 中字段 f 的值写入组装的标识
+func writeKeyValue(b *strings.Builder, value reflect.Value, f *fieldMeta) {
+	fv, ok := fieldValue(value, f.index)
+	if ok && fv.Kind() == reflect.Ptr {
+		ok = !fv.IsNil()
+		fv = fv.Elem()
+	}
+	if !ok {
+		b.WriteString("\x01\x00")
+		return
+	}
+	b.WriteString(fmt.Sprintf("%v\x00", fv.Interface()))
+}
+
+// finalize 将组装完成的集合设置到 struct 的 slice 字段
+func (a *assembler) finalize(node *assemblyNode) {
+	for k, c := range a.children {
+		fieldValueAlloc(node.ptr.Elem(), c.coll.index).Set(c.slice(node.lists[k], c.coll.typ))
+	}
+}
+
+// slice 将 list 转换为 sliceType 类型的 slice
+func (a *assembler) slice(list *assemblyList, sliceType reflect.Type) reflect.Value {
+	slice := reflect.MakeSlice(sliceType, 0, len(list.nodes))
+	for _, node := range list.nodes {
+		a.finalize(node)
+		if sliceType.Elem().Kind() == reflect.Ptr {
+			slice = reflect.Append(slice, node.ptr)
+		} else {
+			slice = reflect.Append(slice, node.ptr.Elem())
+		}
+	}
+	return slice
+}
+
+// assembleList 读取 rows 的所有记录, 组装后追加到 dest 指向的 slice
+func (a *assembler) assembleList(rows *sql.Rows, dest reflect.Value) error {
+	sliceType := dest.Type()
+	roots := newAssemblyList()
+	for rows.Next() {
+		row := a.newRow()
+		scanDest := make([]any, len(a.plan.fields))
+		for i := range scanDest {
+			scanDest[i] = &sql.RawBytes{}
+		}
+		a.fill(row, scanDest)
+		if err := rows.Scan(scanDest...); err != nil {
+			return err
+		}
+		if err := a.assign(row); err != nil {
+			return err
+		}
+		rowKey := strings.Builder{}
+		a.writeRowKey(&rowKey, row)
+		a.merge(roots, row, rowKey.String())
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	dest.Set(reflect.AppendSlice(dest, a.slice(roots, sliceType)))
+	return nil
+}
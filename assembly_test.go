@@ -0,0 +1,125 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type assemblyItemOption struct {
+	Name string
+}
+
+type assemblyItem struct {
+	ID      int64 `colname:"id,pk"`
+	Sku     string
+	Options []assemblyItemOption `colname:"opt"`
+}
+
+type assemblyOrder struct {
+	ID      int64 `colname:"id,pk"`
+	OrderNo string
+	Items   []*assemblyItem
+}
+
+func TestAssembleList(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "order_no", "items__id", "items__sku", "items__opt__name"},
+		[]driver.Value{int64(1), "A", int64(11), "S1", "red"},
+		[]driver.Value{int64(1), "A", int64(11), "S1", "blue"},
+		[]driver.Value{int64(1), "A", int64(12), "S2", nil},
+		[]driver.Value{int64(2), "B", nil, nil, nil},
+		[]driver.Value{int64(3), "C", int64(31), "S3", nil})
+	var orders []assemblyOrder
+	err := NewMySqlSession(NewTxSession(db, false)).Select("*").From("orders").AsList(&orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("orders = %+v", orders)
+	}
+	first := orders[0]
+	if first.OrderNo != "A" || len(first.Items) != 2 || first.Items[1].Sku != "S2" {
+		t.Errorf("orders[0] = %+v", first)
+	}
+	if options := first.Items[0].Options; len(options) != 2 || options[1].Name != "blue" {
+		t.Errorf("options = %+v", options)
+	}
+	if len(first.Items[1].Options) != 0 {
+		t.Errorf("options = %+v", first.Items[1].Options)
+	}
+	if len(orders[1].Items) != 0 || len(orders[2].Items) != 1 {
+		t.Errorf("orders = %+v", orders)
+	}
+}
+
+type assemblyTag struct {
+	Label string
+}
+
+type assemblyNote struct {
+	Text string
+}
+
+type assemblyPost struct {
+	ID    int64 `colname:"id,pk"`
+	Tags  []assemblyTag
+	Notes []assemblyNote
+}
+
+func TestAssembleSiblingCollections(t *testing.T) {
+	db, fake := newFakeDB()
+	// 两个集合字段 JOIN 产生 2 x 3 条记录
+	fake.addResult([]string{"id", "tags__label", "notes__text"},
+		[]driver.Value{int64(1), "go", "n1"},
+		[]driver.Value{int64(1), "go", "n2"},
+		[]driver.Value{int64(1), "go", "n3"},
+		[]driver.Value{int64(1), "sql", "n1"},
+		[]driver.Value{int64(1), "sql", "n2"},
+		[]driver.Value{int64(1), "sql", "n3"})
+	var posts []assemblyPost
+	if err := NewMySqlSession(NewTxSession(db, false)).Select("*").From("posts").AsList(&posts); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || len(posts[0].Tags) != 2 || len(posts[0].Notes) != 3 {
+		t.Fatalf("posts = %+v", posts)
+	}
+	if posts[0].Tags[1].Label != "sql" || posts[0].Notes[2].Text != "n3" {
+		t.Errorf("posts = %+v", posts)
+	}
+}
+
+func TestAssembleIdenticalChildren(t *testing.T) {
+	db, fake := newFakeDB()
+	// 没有主键的集合元素完全相同时, 不是 JOIN 产生的重复, 都保留
+	fake.addResult([]string{"id", "order_no", "items__id", "items__sku", "items__opt__name"},
+		[]driver.Value{int64(1), "A", int64(11), "S1", "red"},
+		[]driver.Value{int64(1), "A", int64(11), "S1", "red"},
+		[]driver.Value{int64(1), "A", int64(12), "S2", "red"})
+	var orders []assemblyOrder
+	if err := NewMySqlSession(NewTxSession(db, false)).Select("*").From("orders").AsList(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || len(orders[0].Items) != 2 {
+		t.Fatalf("orders = %+v", orders)
+	}
+	if options := orders[0].Items[0].Options; len(options) != 2 || options[1].Name != "red" {
+		t.Errorf("options = %+v", options)
+	}
+	if options := orders[0].Items[1].Options; len(options) != 1 {
+		t.Errorf("options = %+v", options)
+	}
+
+}
+
+func TestAssemblerCached(t *testing.T) {
+	columns := []string{"id", "tags__label", "notes__text"}
+	typ := reflect.TypeOf(assemblyPost{})
+	a := getAssembler(typ, columns, scanOptions{})
+	if getAssembler(typ, columns, scanOptions{}) != a {
+		t.Error("expected cached assembler")
+	}
+	if getAssembler(typ, columns[:2], scanOptions{}) == a {
+		t.Error("expected a new assembler for different columns")
+	}
+}
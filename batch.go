@@ -0,0 +1,73 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// BatchResult 批量执行中一条 SQL 的执行结果
+type BatchResult struct {
+	// SQL 执行的 SQL
+	SQL string
+	// RowsAffected 操作的记录数
+	RowsAffected int64
+	// Err 执行失败的错误
+	Err error
+}
+
+// batchStatement AddBatch 加入的 SQL 及参数
+type batchStatement struct {
+	sqlText string
+	args    []any
+}
+
+// addBatch 将 SQL 加入批量队列, 并重置当前 SQL 以构建下一条
+func (bss *baseSqlSession) addBatch(sqlText string, args []any) {
+	bss.batch = append(bss.batch, batchStatement{sqlText: sqlText, args: args})
+	bss.Reset()
+}
+
+// ExecuteBatchContext 按加入顺序执行 AddBatch 加入的 SQL, 相同的 SQL 共用一条预处理语句,
+// 返回每条 SQL 的执行结果。遇到错误时停止执行, 返回已执行的结果及该错误。
+// 执行后清空批量队列
+func (bss *baseSqlSession) ExecuteBatchContext(ctx context.Context) ([]BatchResult, error) {
+	batch := bss.batch
+	bss.batch = nil
+	stmts := map[string]*sql.Stmt{}
+	defer func() {
+		for _, stmt := range stmts {
+			_ = stmt.Close()
+		}
+	}()
+
+	results := make([]BatchResult, 0, len(batch))
+	for _, s := range batch {
+		if bss.logSql {
+			logSql(s.sqlText, s.args)
+		}
+		result := BatchResult{SQL: s.sqlText}
+		stmt, ok := stmts[s.sqlText]
+		if !ok {
+			stmt, result.Err = bss.dbSession.PrepareContext(ctx, s.sqlText)
+			if result.Err == nil {
+				stmts[s.sqlText] = stmt
+			}
+		}
+		if result.Err == nil {
+			var r sql.Result
+			if r, result.Err = stmt.ExecContext(ctx, s.args...); result.Err == nil {
+				result.RowsAffected, result.Err = r.RowsAffected()
+			}
+		}
+		results = append(results, result)
+		if result.Err != nil {
+			return results, result.Err
+		}
+	}
+	return results, nil
+}
+
+// ExecuteBatch 按加入顺序执行 AddBatch 加入的 SQL, 返回每条 SQL 的执行结果
+func (bss *baseSqlSession) ExecuteBatch() ([]BatchResult, error) {
+	return bss.ExecuteBatchContext(context.Background())
+}
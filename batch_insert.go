@@ -0,0 +1,129 @@
+package trysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultMaxParams MySQL 预处理语句和 PostgreSQL 的参数数量上限
+	defaultMaxParams = 65535
+	// defaultMaxPacketBytes MySQL max_allowed_packet 的默认值
+	defaultMaxPacketBytes = 4 << 20
+)
+
+// BatchInsertOptions DoneBatchInsert 拆分 INSERT 的限制
+type BatchInsertOptions struct {
+	// MaxParams 每条 INSERT 的参数数量上限, 默认为 65535
+	MaxParams int
+	// MaxPacketBytes 每条 INSERT 的参数估算大小上限, 默认为 4 MiB, 应小于 MySQL 的 max_allowed_packet
+	MaxPacketBytes int
+}
+
+// DoneBatchInsertContext 将 IntoMultiValues 构建的 INSERT 按照 opts 的限制拆分为多条依次执行, newSession 创建执行每条 INSERT 的 SqlSession。
+// AppendRaw 追加的子句追加到拆分后的每条 INSERT
+func (bss *baseSqlSession) DoneBatchInsertContext(ctx context.Context, opts BatchInsertOptions, newSession func() SqlSession) (int64, error) {
+	stmt := bss.sql.Statement()
+	if !stmt.IsInsert() {
+		return 0, fmt.Errorf("batch insert requires an INSERT statement")
+	}
+	if err := bss.checkParams(); err != nil {
+		return 0, err
+	}
+	table := stmt.Tables()[0]
+	columns := stmt.Columns()
+	rows := make([][]any, 0, len(stmt.Values()))
+	for _, placeholders := range stmt.Values() {
+		row := make([]any, len(placeholders))
+		for i, ph := range placeholders {
+			value, ok := bss.argMap[ph]
+			if !ok {
+				return 0, fmt.Errorf("batch insert supports only parameter values, but %s", ph)
+			}
+			row[i] = value
+		}
+		rows = append(rows, row)
+	}
+	// AppendRaw 追加的 ON CONFLICT, ON DUPLICATE KEY UPDATE 等子句追加到每条 INSERT
+	tail := make([]batchRawSql, len(bss.rawSql))
+	for i, raw := range bss.rawSql {
+		tail[i].sql = raw
+		for _, ph := range getPlaceholder(raw) {
+			tail[i].args = append(tail[i].args, bss.argMap[ph])
+		}
+	}
+	logSql := bss.logSql
+	bss.Reset()
+
+	if opts.MaxParams <= 0 {
+		opts.MaxParams = defaultMaxParams
+	}
+	if opts.MaxPacketBytes <= 0 {
+		opts.MaxPacketBytes = defaultMaxPacketBytes
+	}
+	// 每条 INSERT 都包含追加子句的参数
+	for _, raw := range tail {
+		opts.MaxParams -= len(raw.args)
+	}
+	var total int64
+	for chunk, start := 0, 0; start < len(rows); chunk++ {
+		end := start + batchChunkSize(rows[start:], opts)
+		session := newSession().LogSql(logSql).InsertInto(table).IntoColumns(columns...).IntoMultiValues(rows[start:end])
+		for _, raw := range tail {
+			session.AppendRaw(raw.sql, raw.args...)
+		}
+		affected, err := session.DoneRowsAffectedContext(ctx)
+		total += affected
+		if err != nil {
+			return total, fmt.Errorf("batch insert rows [%d, %d): %w", start, end, err)
+		}
+		start = end
+	}
+	return total, nil
+}
+
+// batchRawSql AppendRaw 追加的 SQL 及参数
+type batchRawSql struct {
+	sql  string
+	args []any
+}
+
+// batchChunkSize 返回 rows 开头满足 opts 限制的记录数, 至少为 1
+func batchChunkSize(rows [][]any, opts BatchInsertOptions) int {
+	params, bytes := 0, 0
+	for n, row := range rows {
+		size := 0
+		for _, value := range row {
+			size += estimateSize(value)
+		}
+		if n > 0 && (params+len(row) > opts.MaxParams || bytes+size > opts.MaxPacketBytes) {
+			return n
+		}
+		params += len(row)
+		bytes += size
+	}
+	return len(rows)
+}
+
+// estimateSize 估算参数值发送到数据库的字节数
+func estimateSize(value any) int {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return 8
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case string:
+		return len(v) + 4
+	case []byte:
+		return len(v) + 4
+	case time.Time:
+		return 12
+	default:
+		return 8
+	}
+}
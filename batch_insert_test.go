@@ -0,0 +1,85 @@
+package trysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoneBatchInsert(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.rowsAffected = 3
+	rows := make([][]any, 10)
+	for i := range rows {
+		rows[i] = []any{i, "name", time.Now()}
+	}
+	affected, err := NewPostgreSqlSession(NewTxSession(db, false)).InsertInto("t").IntoColumns("id", "name", "created").
+		IntoMultiValues(rows).DoneBatchInsert(BatchInsertOptions{MaxParams: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.executed()
+	if affected != 12 || len(execs) != 4 {
+		t.Fatalf("affected = %v, execs = %v", affected, len(execs))
+	}
+	if !strings.Contains(execs[0].query, ", ($7, $8, $9)") || len(execs[3].args) != 3 || execs[3].args[0] != 9 {
+		t.Errorf("execs = %v", execs)
+	}
+}
+
+func TestDoneBatchInsertInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false)
+	rows := [][]any{{strings.Repeat("a", 100)}, {strings.Repeat("b", 100)}, {strings.Repeat("c", 100)}}
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		affected, err := session.InsertInto("t").IntoColumns("name").IntoMultiValues(rows).
+			DoneBatchInsertContext(ctx, BatchInsertOptions{MaxPacketBytes: 250})
+		if affected != 2 {
+			t.Errorf("affected = %v", affected)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.executed()) != 2 || fake.begins != 1 || fake.commits != 1 {
+		t.Errorf("execs = %v, begins = %v, commits = %v", fake.executed(), fake.begins, fake.commits)
+	}
+
+	_, err = NewMySqlSession(NewTxSession(db, false)).Select("*").From("t").DoneBatchInsert(BatchInsertOptions{})
+	if err == nil {
+		t.Error("expected error for SELECT")
+	}
+}
+
+func TestDoneBatchInsertKeepsRawTail(t *testing.T) {
+	db, fake := newFakeDB()
+	rows := [][]any{{1}, {2}, {3}}
+	upsert := "ON CONFLICT (id) DO UPDATE SET updated = #{updated}"
+	_, err := NewPostgreSqlSession(NewTxSession(db, false)).InsertInto("t").IntoColumns("id").
+		IntoMultiValues(rows).AppendRaw(upsert, "now").DoneBatchInsert(BatchInsertOptions{MaxParams: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.executed()
+	if len(execs) != 2 {
+		t.Fatalf("execs = %v", execs)
+	}
+	if !strings.HasSuffix(execs[0].query, "ON CONFLICT (id) DO UPDATE SET updated = $3") || execs[0].args[2] != "now" {
+		t.Errorf("execs[0] = %v", execs[0])
+	}
+	if !strings.HasSuffix(execs[1].query, "ON CONFLICT (id) DO UPDATE SET updated = $2") || execs[1].args[1] != "now" {
+		t.Errorf("execs[1] = %v", execs[1])
+	}
+
+	// 不需要拆分时同样保留追加的子句
+	_, err = NewMySqlSession(NewTxSession(db, false)).InsertInto("t").IntoColumns("id").
+		IntoMultiValues(rows).AppendRaw("ON DUPLICATE KEY UPDATE id = id").DoneBatchInsert(BatchInsertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execs = fake.executed(); len(execs) != 3 || !strings.HasSuffix(execs[2].query, "ON DUPLICATE KEY UPDATE id = id") {
+		t.Errorf("execs = %v", execs)
+	}
+}
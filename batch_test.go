@@ -0,0 +1,33 @@
+package trysql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.rowsAffected = 2
+	ssf := NewSqlSessionFactory(Postgresql, db, time.Second, false)
+	var results []BatchResult
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		for i := 0; i < 3; i++ {
+			session.Update("t").Set("name", "a").Where("id = #{id}", i).AddBatch()
+		}
+		session.DeleteFrom("t").Where("id = #{id}", 9).AddBatch()
+		var err error
+		results, err = session.ExecuteBatchContext(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[0].SQL != results[2].SQL || results[3].RowsAffected != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	execs := fake.executed()
+	if len(execs) != 4 || fake.prepares != 2 || execs[2].args[1] != 2 || fake.commits != 1 {
+		t.Errorf("execs = %v, prepares = %v, commits = %v", execs, fake.prepares, fake.commits)
+	}
+}
@@ -0,0 +1,137 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Converter Go 类型与数据库值之间的转换
+type Converter struct {
+	// DbToGo 将 rows.Scan 得到的数据库值转换为 Go 类型的值, src 可能为 nil
+	DbToGo func(src any) (any, error)
+	// GoToDb 将 Go 类型的值转换为 SQL 参数
+	GoToDb func(value any) (driver.Value, error)
+}
+
+// ConverterRegistry Converter 的注册表, 映射 struct 查询结果和绑定 SQL 参数时使用, 线程安全
+type ConverterRegistry struct {
+	mu      sync.Mutex
+	current atomic.Value // *converterSet
+}
+
+// converterSet ConverterRegistry 某一时刻注册的 Converter, 注册新的 Converter 时整体替换
+type converterSet struct {
+	converters map[reflect.Type]*Converter
+}
+
+// NewConverterRegistry 新建一个空的 ConverterRegistry
+func NewConverterRegistry() *ConverterRegistry {
+	r := &ConverterRegistry{}
+	r.current.Store(&converterSet{converters: map[reflect.Type]*Converter{}})
+	return r
+}
+
+// Register 注册 goType 的转换器, dbToGo 或者 goToDb 为 nil 时对应方向不转换
+func (r *ConverterRegistry) Register(goType reflect.Type, dbToGo func(src any) (any, error), goToDb func(value any) (driver.Value, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.snapshot()
+	converters := make(map[reflect.Type]*Converter, len(current.converters)+1)
+	for t, c := range current.converters {
+		converters[t] = c
+	}
+	converters[goType] = &Converter{DbToGo: dbToGo, GoToDb: goToDb}
+	r.current.Store(&converterSet{converters: converters})
+}
+
+func (r *ConverterRegistry) snapshot() *converterSet {
+	if r == nil {
+		return nil
+	}
+	return r.current.Load().(*converterSet)
+}
+
+var defaultConverters = NewConverterRegistry()
+
+// RegisterConverter 在默认的 ConverterRegistry 中注册 goType 的转换器,
+// 未通过 WithConverters 指定 ConverterRegistry 的 SqlSessionFactory 和 SqlSession 使用默认的 ConverterRegistry
+//
+//	RegisterConverter(reflect.TypeOf(Status(0)),
+//		func(src any) (any, error) { return ParseStatus(src) },
+//		func(value any) (driver.Value, error) { return int64(value.(Status)), nil })
+func RegisterConverter(goType reflect.Type, dbToGo func(src any) (any, error), goToDb func(value any) (driver.Value, error)) {
+	defaultConverters.Register(goType, dbToGo, goToDb)
+}
+
+// dbToGo 返回 typ 或者 typ 指向的类型的 DbToGo 转换器
+func (cs *converterSet) dbToGo(typ reflect.Type) *Converter {
+	if cs == nil || len(cs.converters) == 0 {
+		return nil
+	}
+	if c, ok := cs.converters[typ]; ok && c.DbToGo != nil {
+		return c
+	}
+	if typ.Kind() == reflect.Ptr {
+		if c, ok := cs.converters[typ.Elem()]; ok && c.DbToGo != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// bind 使用 GoToDb 转换 SQL 参数, 转换失败时返回的参数在执行时报告错误
+func (cs *converterSet) bind(arg any) any {
+	if cs == nil || len(cs.converters) == 0 || arg == nil {
+		return arg
+	}
+	c, ok := cs.converters[reflect.TypeOf(arg)]
+	if !ok || c.GoToDb == nil {
+		return arg
+	}
+	value, err := c.GoToDb(arg)
+	if err != nil {
+		return bindError{err: fmt.Errorf("convert %T: %w", arg, err)}
+	}
+	return value
+}
+
+// bindError 转换失败的 SQL 参数
+type bindError struct {
+	err error
+}
+
+func (b bindError) Value() (driver.Value, error) {
+	return nil, b.err
+}
+
+// convertTo 使用 DbToGo 将 src 转换为 typ 类型的值
+func (c *Converter) convertTo(typ reflect.Type, src any) (reflect.Value, error) {
+	value, err := c.DbToGo(src)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	target := typ
+	if typ.Kind() == reflect.Ptr {
+		target = typ.Elem()
+	}
+	if value == nil {
+		return reflect.Zero(typ), nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Type() == typ {
+		return rv, nil
+	}
+	if !rv.Type().ConvertibleTo(target) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to %v", value, target)
+	}
+	rv = rv.Convert(target)
+	if typ.Kind() == reflect.Ptr {
+		p := reflect.New(target)
+		p.Elem().Set(rv)
+		return p, nil
+	}
+	return rv, nil
+}
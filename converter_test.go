@@ -0,0 +1,132 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type orderStatus int
+
+const (
+	statusNew orderStatus = iota + 1
+	statusPaid
+)
+
+func (s orderStatus) String() string {
+	switch s {
+	case statusNew:
+		return "new"
+	case statusPaid:
+		return "paid"
+	}
+	return "unknown"
+}
+
+type statusOrder struct {
+	ID     int64
+	Status orderStatus
+	Prev   *orderStatus
+}
+
+func newStatusConverters() *ConverterRegistry {
+	registry := NewConverterRegistry()
+	registry.Register(reflect.TypeOf(orderStatus(0)),
+		func(src any) (any, error) {
+			switch src {
+			case "new":
+				return statusNew, nil
+			case "paid":
+				return statusPaid, nil
+			case nil:
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown status %v", src)
+		},
+		func(value any) (driver.Value, error) {
+			s := value.(orderStatus)
+			if s == 0 {
+				return nil, errors.New("empty status")
+			}
+			return s.String(), nil
+		})
+	return registry
+}
+
+func TestConverterScan(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "status", "prev"},
+		[]driver.Value{int64(1), "paid", "new"}, []driver.Value{int64(2), "new", nil})
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false, WithConverters(newStatusConverters()))
+
+	var orders []statusOrder
+	if err := ssf.NewSqlSession().Select("*").From("orders").AsList(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if orders[0].Status != statusPaid || orders[0].Prev == nil || *orders[0].Prev != statusNew {
+		t.Errorf("orders[0] = %+v", orders[0])
+	}
+	if orders[1].Status != statusNew || orders[1].Prev != nil {
+		t.Errorf("orders[1] = %+v", orders[1])
+	}
+
+	fake.addResult([]string{"id", "status"}, []driver.Value{int64(3), "lost"})
+	var order statusOrder
+	err := ssf.NewSqlSession().Select("*").From("orders").AsSingle(&order)
+	if err == nil || err.Error() != "field Status: unknown status lost" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestConverterBind(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Postgresql, db, time.Second, false, WithConverters(newStatusConverters()))
+
+	err := ssf.NewSqlSession().Update("orders").Set("status", statusPaid).Where("id = #{id}", 1).Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := fake.executed()[0].args; args[0] != "paid" {
+		t.Errorf("args = %v", args)
+	}
+
+	_, _, err = ssf.NewSqlSession().Update("orders").Set("status", orderStatus(0)).Build()
+	if err == nil {
+		t.Error("expected bind error")
+	}
+}
+
+func TestRegisterConverter(t *testing.T) {
+	type cents int64
+	RegisterConverter(reflect.TypeOf(cents(0)),
+		func(src any) (any, error) {
+			f, err := strconv.ParseFloat(string(src.([]byte)), 64)
+			return cents(f*100 + 0.5), err
+		},
+		func(value any) (driver.Value, error) {
+			return fmt.Sprintf("%.2f", float64(value.(cents))/100), nil
+		})
+	type product struct {
+		Price cents
+	}
+
+	db, fake := newFakeDB()
+	fake.addResult([]string{"price"}, []driver.Value{[]byte("12.34")})
+	session := NewMySqlSession(NewTxSession(db, false))
+	var products []product
+	if err := session.Select("price").From("product").AsList(&products); err != nil {
+		t.Fatal(err)
+	}
+	if products[0].Price != 1234 {
+		t.Errorf("price = %v", products[0].Price)
+	}
+
+	_, args, err := session.New().Select("*").From("product").Where("price > #{price}", cents(500)).Build()
+	if err != nil || args[0] != "5.00" {
+		t.Errorf("args = %v, err = %v", args, err)
+	}
+}
@@ -0,0 +1,158 @@
+package trysql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// CSVOptions ExportCSV 的格式选项
+type CSVOptions struct {
+	// Comma 字段分隔符, 默认为 ',', 使用 '\t' 导出 TSV
+	Comma rune
+	// BOM 是否在开头写入 UTF-8 BOM, 以便 Excel 正确识别编码
+	BOM bool
+	// Null NULL 值写入的文本, 默认为空字符串
+	Null string
+}
+
+// exportRows 逐条读取查询结果, 先以列名调用 header, 再以每条记录调用 row
+func (bss *baseSqlSession) exportRows(ctx context.Context, sqlText string, args []any,
+	header func(columns []string) error, row func(values []any) error) (err error) {
+	rows, err := bss.queryContext(ctx, sqlText, args)
+	if err != nil {
+		return err
+	}
+	defer func(rows *sql.Rows) {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+	}(rows)
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := columnTypeNames(rows)
+	if err != nil {
+		return err
+	}
+	if err := header(columns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		values, err := scanValues(rows, types)
+		if err != nil {
+			return err
+		}
+		if err := row(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (bss *baseSqlSession) ExportCSVContext(ctx context.Context, sqlText string, args []any, w io.Writer, opts CSVOptions) error {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	var record []string
+	err := bss.exportRows(ctx, sqlText, args,
+		func(columns []string) error {
+			// 查询成功后再写入 BOM, 查询失败时不写入任何内容
+			if opts.BOM {
+				if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+					return err
+				}
+			}
+			record = make([]string, len(columns))
+			return cw.Write(columns)
+		},
+		func(values []any) error {
+			for i, value := range values {
+				record[i] = formatCSVValue(value, opts.Null)
+			}
+			return cw.Write(record)
+		})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (bss *baseSqlSession) ExportJSONLinesContext(ctx context.Context, sqlText string, args []any, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var keys [][]byte
+	err := bss.exportRows(ctx, sqlText, args,
+		func(columns []string) error {
+			keys = make([][]byte, len(columns))
+			for i, column := range columns {
+				keys[i], _ = json.Marshal(column)
+			}
+			return nil
+		},
+		func(values []any) error {
+			// 按列的顺序输出对象的属性
+			bw.WriteByte('{')
+			for i, value := range values {
+				if i > 0 {
+					bw.WriteByte(',')
+				}
+				b, err := json.Marshal(jsonLineValue(value))
+				if err != nil {
+					return fmt.Errorf("column %s: %w", keys[i], err)
+				}
+				bw.Write(keys[i])
+				bw.WriteByte(':')
+				bw.Write(b)
+			}
+			_, err := bw.WriteString("}\n")
+			return err
+		})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// formatCSVValue 将列值格式化为 CSV 字段, 时间使用 RFC 3339 格式
+func formatCSVValue(value any, null string) string {
+	switch v := value.(type) {
+	case nil:
+		return null
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonLineValue 返回列值的 JSON 表示, 时间使用 RFC 3339 格式, 合法的 UTF-8 []byte 作为字符串输出
+func jsonLineValue(value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+	}
+	return value
+}
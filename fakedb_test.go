@@ -0,0 +1,278 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeResult 一次查询返回的结果集
+type fakeResult struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	// more 之后的结果集
+	more []fakeResult
+}
+
+// fakeExec 记录一次执行的 SQL 及参数
+type fakeExec struct {
+	query string
+	args  []any
+}
+
+// fakeDB 不依赖数据库的 database/sql 驱动，查询按顺序返回预先准备的结果集
+type fakeDB struct {
+	mu           sync.Mutex
+	results      []fakeResult
+	execs        []fakeExec
+	rowsAffected int64
+	lastInsertId int64
+	begins       int
+	commits      int
+	rollbacks    int
+	prepares     int
+	stmtCloses   int
+	// failPrepare 不为空时, 预处理包含该文本的 SQL 返回错误
+	failPrepare string
+	// txOptions 为 true 时连接实现 driver.ConnBeginTx, 记录开启事务的选项
+	txOptions bool
+	beginOpts []driver.TxOptions
+}
+
+func newFakeDB() (*sql.DB, *fakeDB) {
+	fake := &fakeDB{rowsAffected: 1}
+	return sql.OpenDB(fake), fake
+}
+
+// addResult 准备下一次查询的结果集
+func (f *fakeDB) addResult(columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, fakeResult{columns: columns, rows: rows})
+}
+
+// addTypedResult 准备下一次查询的结果集, types 为每一列的数据库类型名
+func (f *fakeDB) addTypedResult(columns, types []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, fakeResult{columns: columns, types: types, rows: rows})
+}
+
+// addResultSets 准备下一次查询返回的多个结果集
+func (f *fakeDB) addResultSets(results ...fakeResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := results[0]
+	result.more = results[1:]
+	f.results = append(f.results, result)
+}
+
+func (f *fakeDB) nextResult() fakeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.results) == 0 {
+		return fakeResult{}
+	}
+	result := f.results[0]
+	f.results = f.results[1:]
+	return result
+}
+
+func (f *fakeDB) record(query string, args []driver.NamedValue) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.execs = append(f.execs, fakeExec{query: query, args: values})
+}
+
+func (f *fakeDB) executed() []fakeExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeExec(nil), f.execs...)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	if f.txOptions {
+		return &fakeTxOptionsConn{fakeConn{db: f}}, nil
+	}
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{db: f}
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.prepares++
+	if c.db.failPrepare != "" && strings.Contains(query, c.db.failPrepare) {
+		return nil, fmt.Errorf("syntax error near %q", c.db.failPrepare)
+	}
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.begins++
+	return &fakeTx{db: c.db}, nil
+}
+
+// CheckNamedValue 接受任意类型的参数，便于检查传给驱动的原始参数
+func (c *fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if valuer, ok := nv.Value.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		nv.Value = value
+	}
+	return nil
+}
+
+// fakeTxOptionsConn 支持 driver.TxOptions 的连接
+type fakeTxOptionsConn struct {
+	fakeConn
+}
+
+func (c *fakeTxOptionsConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.begins++
+	c.db.beginOpts = append(c.db.beginOpts, opts)
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.stmtCloses++
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	panic("not used: ExecContext is implemented")
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	panic("not used: QueryContext is implemented")
+}
+
+func (s *fakeStmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	s.db.record(s.query, args)
+	return fakeExecResult{rowsAffected: s.db.rowsAffected, lastInsertId: s.db.lastInsertId}, nil
+}
+
+func (s *fakeStmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	s.db.record(s.query, args)
+	result := s.db.nextResult()
+	return &fakeRows{result: result}, nil
+}
+
+type fakeExecResult struct {
+	rowsAffected int64
+	lastInsertId int64
+}
+
+func (r fakeExecResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r fakeExecResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	pos    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	if r.result.types == nil {
+		return ""
+	}
+	return r.result.types[index]
+}
+
+func (r *fakeRows) HasNextResultSet() bool {
+	return len(r.result.more) > 0
+}
+
+func (r *fakeRows) NextResultSet() error {
+	if len(r.result.more) == 0 {
+		return io.EOF
+	}
+	more := r.result.more
+	r.result, r.pos = more[0], 0
+	r.result.more = more[1:]
+	return nil
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -0,0 +1,103 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Primitive 可以作为 Value, Values 结果的类型
+type Primitive interface {
+	~bool | ~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64 | ~string | ~[]byte | time.Time |
+		sql.NullString | sql.NullInt64 | sql.NullInt32 | sql.NullInt16 | sql.NullByte |
+		sql.NullFloat64 | sql.NullBool | sql.NullTime
+}
+
+// List 执行 SQL，T 是 struct 或 struct 指针, 每条记录映射为一个 T
+func List[T any](ctx context.Context, s SqlSession) ([]T, error) {
+	if err := checkStructType[T](); err != nil {
+		return nil, err
+	}
+	list := make([]T, 0)
+	if err := s.AsListContext(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// One 执行 SQL，T 是 struct 或 struct 指针, 第一条记录映射为 T, 没有记录时返回 T 的零值
+func One[T any](ctx context.Context, s SqlSession) (T, error) {
+	var one T
+	if err := checkStructType[T](); err != nil {
+		return one, err
+	}
+	dest, value := structDest[T]()
+	if ok, err := s.AsSingleOKContext(ctx, dest); !ok || err != nil {
+		return one, err
+	}
+	return value(), nil
+}
+
+// First 执行 SQL，T 是 struct 或 Primitive 类型, 第一条记录映射为 T, ok 表示是否查询到记录
+func First[T any](ctx context.Context, s SqlSession) (first T, ok bool, err error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if isPrimitiveType(typ) {
+		err = s.AsPrimitiveContext(ctx, &first)
+		if errors.Is(err, sql.ErrNoRows) {
+			return first, false, nil
+		}
+		return first, err == nil, err
+	}
+	if err = checkStructType[T](); err != nil {
+		return first, false, err
+	}
+	dest, value := structDest[T]()
+	if ok, err = s.AsSingleOKContext(ctx, dest); !ok || err != nil {
+		return first, ok, err
+	}
+	return value(), true, nil
+}
+
+// Value 执行 SQL，返回第一条记录第一列的值, 没有记录时返回 sql.ErrNoRows
+func Value[T Primitive](ctx context.Context, s SqlSession) (T, error) {
+	var value T
+	err := s.AsPrimitiveContext(ctx, &value)
+	return value, err
+}
+
+// Values 执行 SQL，返回每条记录第一列的值
+func Values[T Primitive](ctx context.Context, s SqlSession) ([]T, error) {
+	values := make([]T, 0)
+	if err := s.AsPrimitiveListContext(ctx, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// structDest 返回用于映射记录的 struct 指针，以及映射完成后取得 T 的函数
+func structDest[T any]() (any, func() T) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Ptr {
+		p := reflect.New(typ.Elem())
+		return p.Interface(), func() T { return p.Interface().(T) }
+	}
+	p := new(T)
+	return p, func() T { return *p }
+}
+
+// checkStructType 检查 T 是 struct 或者 struct 指针
+func checkStructType[T any]() error {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || isPrimitiveType(typ) {
+		return fmt.Errorf("expected struct or pointer to struct, but %v", typ)
+	}
+	return nil
+}
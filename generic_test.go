@@ -0,0 +1,72 @@
+package trysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+type genericUser struct {
+	ID   int64 `colname:"id"`
+	Name string
+}
+
+func TestList(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+	session := NewMySqlSession(NewTxSession(db, false))
+
+	users, err := List[genericUser](context.Background(), session.Select("id", "name").From("user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].ID != 2 || users[1].Name != "b" {
+		t.Errorf("users = %v", users)
+	}
+
+	if _, err := List[int64](context.Background(), session.Select("id").From("user")); err == nil {
+		t.Error("expected error for non struct element")
+	}
+}
+
+func TestOneAndFirst(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewPostgreSqlSession(NewTxSession(db, false))
+	ctx := context.Background()
+
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(7), "x"})
+	user, err := One[*genericUser](ctx, session.Select("id", "name").From("user"))
+	if err != nil || user == nil || user.ID != 7 {
+		t.Fatalf("user = %v, err = %v", user, err)
+	}
+
+	fake.addResult([]string{"id", "name"})
+	first, ok, err := First[genericUser](ctx, session.Select("id", "name").From("user"))
+	if err != nil || ok || first.ID != 0 {
+		t.Errorf("first = %v, ok = %v, err = %v", first, ok, err)
+	}
+
+	fake.addResult([]string{"name"})
+	name, ok, err := First[string](ctx, session.Select("name").From("user"))
+	if err != nil || ok || name != "" {
+		t.Errorf("name = %v, ok = %v, err = %v", name, ok, err)
+	}
+}
+
+func TestValues(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewMySqlSession(NewTxSession(db, false))
+	ctx := context.Background()
+
+	fake.addResult([]string{"count"}, []driver.Value{int64(42)})
+	count, err := Value[int64](ctx, session.Select("count(*)").From("user"))
+	if err != nil || count != 42 {
+		t.Errorf("count = %v, err = %v", count, err)
+	}
+
+	fake.addResult([]string{"name"}, []driver.Value{"a"}, []driver.Value{[]byte("b")})
+	names, err := Values[string](ctx, session.Select("name").From("user"))
+	if err != nil || len(names) != 2 || names[1] != "b" {
+		t.Errorf("names = %v, err = %v", names, err)
+	}
+}
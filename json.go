@@ -0,0 +1,66 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// JSON 将 v 序列化为 JSON 后作为 SQL 参数, 用于 Values, Set 等写入 JSON 列, v 为 nil 时写入 NULL
+//
+//	sqlSession.Update("product").Set("attrs", trysql.JSON(attrs)).Where("id = #{id}", id)
+func JSON(v any) driver.Valuer {
+	return jsonValue{v: v}
+}
+
+type jsonValue struct {
+	v any
+}
+
+func (j jsonValue) Value() (driver.Value, error) {
+	if isNil(j.v) {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	// PostgreSQL 的 []byte 参数按 bytea 处理, 使用 string 以同时支持 json 和 jsonb 列
+	return string(b), nil
+}
+
+// isNil v 是否为 nil 或者值为 nil 的 map, slice, 指针
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// jsonConverter 返回将 JSON 列反序列化为 typ 类型的 Converter, 用于 colname 带有 json 选项的字段
+func jsonConverter(typ reflect.Type) *Converter {
+	return &Converter{DbToGo: func(src any) (any, error) {
+		var data []byte
+		switch s := src.(type) {
+		case nil:
+			return nil, nil
+		case []byte:
+			data = s
+		case string:
+			data = []byte(s)
+		default:
+			return nil, fmt.Errorf("unsupported JSON column value %T", src)
+		}
+		value := reflect.New(typ)
+		if err := json.Unmarshal(data, value.Interface()); err != nil {
+			return nil, err
+		}
+		return value.Elem().Interface(), nil
+	}}
+}
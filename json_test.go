@@ -0,0 +1,63 @@
+package trysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jsonProduct struct {
+	ID     int64             `colname:"id,pk,table=product"`
+	Attrs  map[string]string `colname:"attrs,json"`
+	Tags   []string          `colname:"tags,json"`
+	Size   *tagAddress       `colname:"size,json"`
+	Extras []nestedCustomer  `colname:"extras,json"`
+}
+
+func TestJSONColumnScan(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "attrs", "tags", "size", "extras"},
+		[]driver.Value{int64(1), []byte(`{"color":"red"}`), `["a","b"]`, []byte(`{"City":"Paris"}`), []byte(`[{"ID":2}]`)},
+		[]driver.Value{int64(2), nil, nil, nil, nil})
+	var products []jsonProduct
+	if err := NewPostgreSqlSession(NewTxSession(db, false)).Select("*").From("product").AsList(&products); err != nil {
+		t.Fatal(err)
+	}
+	p := products[0]
+	if p.Attrs["color"] != "red" || len(p.Tags) != 2 || p.Size == nil || p.Size.City != "Paris" || p.Extras[0].ID != 2 {
+		t.Errorf("products[0] = %+v", p)
+	}
+	if p := products[1]; p.Attrs != nil || p.Tags != nil || p.Size != nil {
+		t.Errorf("products[1] = %+v", p)
+	}
+
+	fake.addResult([]string{"id", "attrs"}, []driver.Value{int64(3), []byte(`{"color":`)})
+	var product jsonProduct
+	err := NewMySqlSession(NewTxSession(db, false)).Select("*").From("product").AsSingle(&product)
+	if err == nil || !strings.HasPrefix(err.Error(), "field Attrs: ") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestJSONColumnWrite(t *testing.T) {
+	db, fake := newFakeDB()
+	repo := NewRepository[jsonProduct](NewSqlSessionFactory(Mysql, db, time.Second, false))
+	product := jsonProduct{ID: 1, Attrs: map[string]string{"color": "red"}, Tags: []string{"a"}}
+	if err := repo.Insert(context.Background(), &product); err != nil {
+		t.Fatal(err)
+	}
+	err := NewMySqlSession(NewTxSession(db, false)).Update("product").Set("tags", JSON([]string{"b"})).
+		Where("id = #{id}", 1).Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.executed()
+	if args := execs[0].args; args[1] != `{"color":"red"}` || args[2] != `["a"]` || args[3] != nil || args[4] != nil {
+		t.Errorf("insert args = %v", args)
+	}
+	if args := execs[1].args; args[0] != `["b"]` {
+		t.Errorf("update args = %v", args)
+	}
+}
@@ -0,0 +1,645 @@
+package trysql
+
+import (
+	"container/list"
+	"database/sql"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/iancoleman/strcase"
+)
+
+// colTagName struct 字段映射数据库列使用的 tag, 格式: colname:"列名,选项1,选项2=值"
+//
+// 列名为 "-" 时忽略该字段，列名为空时使用字段名的 snake case。支持的选项:
+//
+//	pk         主键列
+//	auto       自增列, 插入时忽略，插入后回填
+//	readonly   只读列, 插入和更新时忽略
+//	omitempty  字段为零值时, 插入和更新时忽略
+//	json       JSON 列, 查询时反序列化, 插入和更新时序列化, 字段可以是 struct, map 或 slice
+//	prefix=xx  嵌入的 struct 中所有字段的列名前缀
+//	table=xx   struct 对应的表名，可以出现在任意一个字段上
+//	default=xx 字段为零值时, 插入和更新时写入的值, 按字段类型解析, 解析失败时 panic
+const colTagName = "colname"
+
+// colTag 解析后的 colname tag
+type colTag struct {
+	name      string
+	ignore    bool
+	pk        bool
+	auto      bool
+	readonly  bool
+	omitempty bool
+	json      bool
+	prefix    string
+	table     string
+	// hasDefault 是否指定了 default 选项, defaultValue 为其原始文本
+	hasDefault   bool
+	defaultValue string
+}
+
+func parseColTag(tag string) colTag {
+	parts := strings.Split(tag, ",")
+	ct := colTag{name: strings.TrimSpace(parts[0])}
+	if ct.name == "-" && len(parts) == 1 {
+		ct.ignore = true
+		return ct
+	}
+	for _, option := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "pk":
+			ct.pk = true
+		case "auto":
+			ct.auto = true
+		case "readonly":
+			ct.readonly = true
+		case "omitempty":
+			ct.omitempty = true
+		case "json":
+			ct.json = true
+		case "prefix":
+			ct.prefix = value
+		case "table":
+			ct.table = value
+		case "default":
+			ct.hasDefault = true
+			ct.defaultValue = value
+		}
+	}
+	return ct
+}
+
+// columnName 返回字段映射的列名，未指定 colname 时使用字段名的 snake case
+func columnName(field reflect.StructField) (string, colTag) {
+	tag := parseColTag(field.Tag.Get(colTagName))
+	if tag.name == "" {
+		return strcase.ToSnake(field.Name), tag
+	}
+	return tag.name, tag
+}
+
+// fieldMeta struct 中映射到列的字段
+type fieldMeta struct {
+	name   string
+	column string
+	index  []int
+	typ    reflect.Type
+	tag    colTag
+	// nested 是否属于非嵌入的 struct 字段, 这些字段只用于查询
+	nested bool
+	// nullable 是否位于 struct 指针字段中, 所有列都为 NULL 时该 struct 指针保持 nil
+	nullable bool
+	// defaultValue 按字段类型解析后的 default 选项的值
+	defaultValue any
+}
+
+// structMeta struct 与表的映射关系, 嵌入的 struct 字段被展开,
+// 非嵌入的 struct 字段按列名前缀展开, 默认前缀为 "字段列名__", 也可以通过 prefix 选项指定
+type structMeta struct {
+	typ         reflect.Type
+	table       string
+	fields      []*fieldMeta
+	byColumn    map[string]*fieldMeta
+	pks         []*fieldMeta
+	auto        *fieldMeta
+	collections []*collectionMeta
+}
+
+// collectionMeta struct 中元素为 struct 的 slice 字段, 用于 JOIN 查询结果的组装
+type collectionMeta struct {
+	index []int
+	typ   reflect.Type
+	// elem slice 元素的 struct 类型
+	elem reflect.Type
+	// prefix 元素的列名前缀, 默认为 "字段列名__"
+	prefix string
+}
+
+// structMetas 缓存 struct 类型的 *structMeta
+var structMetas sync.Map
+
+// getStructMeta 返回 typ 的映射关系, 每个类型只解析一次
+func getStructMeta(typ reflect.Type) *structMeta {
+	if meta, ok := structMetas.Load(typ); ok {
+		return meta.(*structMeta)
+	}
+	meta, _ := structMetas.LoadOrStore(typ, newStructMeta(typ))
+	return meta.(*structMeta)
+}
+
+func newStructMeta(typ reflect.Type) *structMeta {
+	meta := &structMeta{typ: typ, byColumn: map[string]*fieldMeta{}}
+	meta.collect(typ, nil, structScope{types: map[reflect.Type]bool{}})
+	if meta.table == "" {
+		meta.table = strcase.ToSnake(typ.Name())
+	}
+	return meta
+}
+
+// structScope 展开 struct 字段时的上下文
+type structScope struct {
+	prefix   string
+	nested   bool
+	nullable bool
+	// types 展开路径上的 struct 类型, 用于避免递归类型无限展开
+	types map[reflect.Type]bool
+}
+
+// nestedStructType 返回字段对应的需要展开的 struct 类型
+func nestedStructType(field reflect.StructField) (reflect.Type, bool) {
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ, typ.Kind() == reflect.Struct && !isPrimitiveType(typ)
+}
+
+// collectionElemType 返回元素为 struct 或 struct 指针的 slice 字段的元素 struct 类型
+func collectionElemType(field reflect.StructField) (reflect.Type, bool) {
+	if field.Type.Kind() != reflect.Slice {
+		return nil, false
+	}
+	elem := field.Type.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem, elem.Kind() == reflect.Struct && !isPrimitiveType(elem)
+}
+
+func (m *structMeta) collect(typ reflect.Type, index []int, scope structScope) {
+	scope.types[typ] = true
+	defer delete(scope.types, typ)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := parseColTag(field.Tag.Get(colTagName))
+		if tag.table != "" && !scope.nested {
+			m.table = tag.table
+		}
+		if tag.ignore {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+		if field.Anonymous {
+			// 未导出的 struct 指针无法初始化
+			if embedded, ok := nestedStructType(field); ok && (field.IsExported() || field.Type.Kind() != reflect.Ptr) {
+				if !scope.types[embedded] {
+					inner := scope
+					inner.prefix += tag.prefix
+					m.collect(embedded, fieldIndex, inner)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		column, _ := columnName(field)
+		// JSON 列作为一个整体映射, 不展开
+		if elem, ok := collectionElemType(field); ok && !tag.json {
+			if !scope.nested {
+				prefix := tag.prefix
+				if prefix == "" {
+					prefix = column + "__"
+				}
+				m.collections = append(m.collections, &collectionMeta{index: fieldIndex, typ: field.Type, elem: elem, prefix: prefix})
+			}
+			continue
+		}
+		if nested, ok := nestedStructType(field); ok && !tag.json {
+			if !scope.types[nested] {
+				inner := scope
+				if tag.prefix != "" {
+					inner.prefix += tag.prefix
+				} else {
+					inner.prefix += column + "__"
+				}
+				inner.nested = true
+				inner.nullable = scope.nullable || field.Type.Kind() == reflect.Ptr
+				m.collect(nested, fieldIndex, inner)
+			}
+			continue
+		}
+		column = scope.prefix + column
+		// 与 Go 的字段提升规则一致，层级浅的字段优先
+		if exists, ok := m.byColumn[column]; ok && len(exists.index) <= len(fieldIndex) {
+			continue
+		}
+		fm := &fieldMeta{name: field.Name, column: column, index: fieldIndex, typ: field.Type, tag: tag,
+			nested: scope.nested, nullable: scope.nullable}
+		if tag.hasDefault {
+			value, err := parseDefault(field.Type, tag.defaultValue)
+			if err != nil {
+				panic(fmt.Sprintf("invalid default of field %s: %v", field.Name, err))
+			}
+			fm.defaultValue = value
+		}
+		m.byColumn[column] = fm
+		m.fields = m.replace(m.fields, fm)
+		if scope.nested {
+			continue
+		}
+		if tag.pk {
+			m.pks = m.replace(m.pks, fm)
+		}
+		if tag.auto {
+			m.auto = fm
+		}
+	}
+}
+
+// replace 用 fm 替换 fields 中同列名的字段，不存在时追加
+func (m *structMeta) replace(fields []*fieldMeta, fm *fieldMeta) []*fieldMeta {
+	for i, f := range fields {
+		if f.column == fm.column {
+			fields[i] = fm
+			return fields
+		}
+	}
+	return append(fields, fm)
+}
+
+// writeValue 返回插入和更新时 entity 中该字段写入的值, 不需要写入时返回 false
+func (f *fieldMeta) writeValue(entity reflect.Value) (any, bool) {
+	if f.tag.auto || f.tag.readonly || f.nested {
+		return nil, false
+	}
+	fv, ok := fieldValue(entity, f.index)
+	if !ok {
+		if f.tag.hasDefault {
+			return f.defaultValue, true
+		}
+		return nil, !f.tag.omitempty
+	}
+	value := fv.Interface()
+	if !isNotZero(value) {
+		if f.tag.hasDefault {
+			return f.defaultValue, true
+		}
+		if f.tag.omitempty {
+			return nil, false
+		}
+	}
+	if f.tag.json {
+		return JSON(value), true
+	}
+	return value, true
+}
+
+// parseDefault 将 default 选项的文本按 typ 解析, 数值和布尔类型以外的字段使用原始文本
+func parseDefault(typ reflect.Type, text string) (any, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(text, 10, typ.Bits())
+		return reflect.ValueOf(v).Convert(typ).Interface(), err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(text, 10, typ.Bits())
+		return reflect.ValueOf(v).Convert(typ).Interface(), err
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(text, typ.Bits())
+		return reflect.ValueOf(v).Convert(typ).Interface(), err
+	case reflect.Bool:
+		v, err := strconv.ParseBool(text)
+		return reflect.ValueOf(v).Convert(typ).Interface(), err
+	case reflect.String:
+		return reflect.ValueOf(text).Convert(typ).Interface(), nil
+	default:
+		return text, nil
+	}
+}
+
+// columns 返回所有映射的列名, 不包括非嵌入的 struct 字段
+func (m *structMeta) columns() []string {
+	columns := make([]string, 0, len(m.fields))
+	for _, f := range m.fields {
+		if !f.nested {
+			columns = append(columns, f.column)
+		}
+	}
+	return columns
+}
+
+// scanPlan 查询结果的列到 struct 字段的映射, 每个 struct 类型和查询列的组合只解析一次
+type scanPlan struct {
+	// fields 与查询列一一对应, nil 表示没有映射的列
+	fields []*fieldMeta
+	// converters 与查询列一一对应, 不为 nil 时列先映射到 any, 再由 Converter 转换
+	converters []*Converter
+	// nullable 所有字段都先映射到临时变量, 用于判断记录是否全部为 NULL
+	nullable bool
+	// mismatch 查询列与字段不一致的情况, 只在需要时检查一次
+	mismatch     *MappingError
+	mismatchOnce sync.Once
+	warnOnce     sync.Once
+}
+
+// scanOptions 影响查询结果映射的 SqlSession 配置
+type scanOptions struct {
+	converters *converterSet
+	// pgArray 使用 pq.Array 映射 slice 字段
+	pgArray bool
+}
+
+func newScanPlan(meta *structMeta, columns []string, nullable bool, opts scanOptions) *scanPlan {
+	plan := &scanPlan{fields: make([]*fieldMeta, len(columns)), nullable: nullable}
+	for i, column := range columns {
+		f := meta.byColumn[column]
+		plan.fields[i] = f
+		if f == nil {
+			continue
+		}
+		c := opts.converters.dbToGo(f.typ)
+		if f.tag.json {
+			c = jsonConverter(f.typ)
+		} else if c == nil && opts.pgArray && isArrayType(f.typ) {
+			c = arrayConverter(f.typ)
+		}
+		if c != nil {
+			if plan.converters == nil {
+				plan.converters = make([]*Converter, len(columns))
+			}
+			plan.converters[i] = c
+		}
+	}
+	return plan
+}
+
+type scanPlanKey struct {
+	typ     reflect.Type
+	columns string
+	opts    scanOptions
+}
+
+// maxScanPlans 缓存的 *scanPlan 的最大数量
+const maxScanPlans = 1024
+
+// scanPlans 缓存最近使用的 *scanPlan。
+// key 包含 converterSet, 注册新的 Converter 后旧的 *scanPlan 不再使用, 超出容量时被淘汰
+var scanPlans = newScanPlanCache[*scanPlan](maxScanPlans)
+
+// scanPlanCache 以 scanPlanKey 为键的 LRU 缓存, 用于缓存 *scanPlan 及 *assembler, 线程安全
+type scanPlanCache[V any] struct {
+	capacity int
+
+	mu      sync.Mutex
+	lru     *list.List // *scanPlanEntry[V], 最近使用的在前
+	entries map[scanPlanKey]*list.Element
+}
+
+type scanPlanEntry[V any] struct {
+	key   scanPlanKey
+	value V
+}
+
+func newScanPlanCache[V any](capacity int) *scanPlanCache[V] {
+	return &scanPlanCache[V]{capacity: capacity, lru: list.New(), entries: map[scanPlanKey]*list.Element{}}
+}
+
+func (c *scanPlanCache[V]) load(key scanPlanKey) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*scanPlanEntry[V]).value, true
+}
+
+// loadOrStore 返回已缓存的值, 不存在时缓存 value
+func (c *scanPlanCache[V]) loadOrStore(key scanPlanKey, value V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return elem.Value.(*scanPlanEntry[V]).value
+	}
+	c.entries[key] = c.lru.PushFront(&scanPlanEntry[V]{key: key, value: value})
+	for c.lru.Len() > c.capacity {
+		entry := c.lru.Remove(c.lru.Back()).(*scanPlanEntry[V])
+		delete(c.entries, entry.key)
+	}
+	return value
+}
+
+func (c *scanPlanCache[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// getScanPlan 返回 typ 对应 columns 的 *scanPlan
+func getScanPlan(typ reflect.Type, columns []string, opts scanOptions) *scanPlan {
+	key := scanPlanKey{typ: typ, columns: strings.Join(columns, "\x00"), opts: opts}
+	if plan, ok := scanPlans.load(key); ok {
+		return plan
+	}
+	plan := newScanPlan(getStructMeta(typ), columns, false, opts)
+	return scanPlans.loadOrStore(key, plan)
+}
+
+// checkMapping 按照 mode 处理查询列 columns 与 typ 的字段不一致的情况
+func (p *scanPlan) checkMapping(mode MappingMode, typ reflect.Type, columns []string) error {
+	if mode == MappingLoose {
+		return nil
+	}
+	p.mismatchOnce.Do(func() {
+		p.mismatch = checkMapping(getStructMeta(typ), columns)
+	})
+	if p.mismatch == nil {
+		return nil
+	}
+	if mode == MappingStrict {
+		return p.mismatch
+	}
+	p.warnOnce.Do(func() {
+		log.Printf("WARN %v", p.mismatch)
+	})
+	return nil
+}
+
+// scan 将 rows 的当前记录映射到 rowDest
+func (p *scanPlan) scan(rows *sql.Rows, rowDest reflect.Value) error {
+	scanDest := p.scanDest(rowDest)
+	if err := rows.Scan(scanDest...); err != nil {
+		return err
+	}
+	_, err := p.assign(rowDest, scanDest)
+	return err
+}
+
+// scanDest 返回 rowDest 对应的 rows.Scan 参数, 没有映射的列使用 sql.RawBytes 接收,
+// struct 指针字段中的列和需要转换的列先映射到临时变量, 由 assign 赋值
+func (p *scanPlan) scanDest(rowDest reflect.Value) []any {
+	scanDest := make([]any, len(p.fields))
+	for i, f := range p.fields {
+		switch {
+		case f == nil:
+			scanDest[i] = &sql.RawBytes{}
+		case p.converter(i) != nil:
+			scanDest[i] = new(any)
+		case f.nullable || p.nullable:
+			scanDest[i] = reflect.New(reflect.PointerTo(f.typ)).Interface()
+		default:
+			scanDest[i] = fieldValueAlloc(rowDest, f.index).Addr().Interface()
+		}
+	}
+	return scanDest
+}
+
+func (p *scanPlan) converter(i int) *Converter {
+	if p.converters == nil {
+		return nil
+	}
+	return p.converters[i]
+}
+
+// assign 将临时变量中的值赋值到 rowDest, struct 指针字段中的列为 NULL 时不赋值, 以保持 struct 指针为 nil,
+// 返回是否存在这样不为 NULL 的列
+func (p *scanPlan) assign(rowDest reflect.Value, scanDest []any) (bool, error) {
+	assigned := false
+	for i, f := range p.fields {
+		if f == nil {
+			continue
+		}
+		nullable := f.nullable || p.nullable
+		if c := p.converter(i); c != nil {
+			src := *scanDest[i].(*any)
+			if src == nil && nullable {
+				continue
+			}
+			value, err := c.convertTo(f.typ, src)
+			if err != nil {
+				return assigned, fmt.Errorf("field %s: %w", f.name, err)
+			}
+			fieldValueAlloc(rowDest, f.index).Set(value)
+			assigned = assigned || nullable
+		} else if nullable {
+			if value := reflect.ValueOf(scanDest[i]).Elem(); !value.IsNil() {
+				fieldValueAlloc(rowDest, f.index).Set(value.Elem())
+				assigned = true
+			}
+		}
+	}
+	return assigned, nil
+}
+
+// fieldValue 返回 v 中 index 对应的字段，路径上存在 nil 指针时返回 false
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldValueAlloc 返回 v 中 index 对应的字段，路径上的 nil 指针会被初始化
+func fieldValueAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// MappingMode 查询结果的列与 struct 字段不一致时的处理方式
+type MappingMode uint8
+
+const (
+	// MappingLoose 忽略没有映射的列和没有填充的字段
+	MappingLoose MappingMode = iota
+	// MappingWarn 输出警告日志, 每个 struct 类型和查询列的组合只输出一次
+	MappingWarn
+	// MappingStrict 返回 *MappingError
+	MappingStrict
+)
+
+// MappingError 严格映射模式下查询结果的列与 struct 字段不一致
+type MappingError struct {
+	Type reflect.Type
+	// UnmappedColumns 没有映射到字段的列
+	UnmappedColumns []string
+	// UnfilledFields 没有列填充的字段, 格式为 "字段名(列名)"
+	UnfilledFields []string
+	// DuplicateColumns 重复的列名
+	DuplicateColumns []string
+}
+
+func (e *MappingError) Error() string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("mapping %v:", e.Type))
+	if len(e.UnmappedColumns) > 0 {
+		b.WriteString(fmt.Sprintf(" unmapped columns %v;", e.UnmappedColumns))
+	}
+	if len(e.UnfilledFields) > 0 {
+		b.WriteString(fmt.Sprintf(" unfilled fields %v;", e.UnfilledFields))
+	}
+	if len(e.DuplicateColumns) > 0 {
+		b.WriteString(fmt.Sprintf(" duplicate columns %v;", e.DuplicateColumns))
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// checkMapping 检查 columns 与 meta 的字段是否一一对应, 需要组装时包括集合字段, 一致时返回 nil
+func checkMapping(meta *structMeta, columns []string) *MappingError {
+	e := &MappingError{Type: meta.typ}
+	present := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if present[column] {
+			e.DuplicateColumns = append(e.DuplicateColumns, column)
+		}
+		present[column] = true
+	}
+	mapped := map[string]bool{}
+	meta.checkMapping("", present, mapped, e, map[reflect.Type]bool{}, needAssembly(meta))
+	for _, column := range columns {
+		if !mapped[column] {
+			e.UnmappedColumns = append(e.UnmappedColumns, column)
+			mapped[column] = true
+		}
+	}
+	if len(e.UnmappedColumns) == 0 && len(e.UnfilledFields) == 0 && len(e.DuplicateColumns) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (m *structMeta) checkMapping(prefix string, present, mapped map[string]bool, e *MappingError, types map[reflect.Type]bool, collections bool) {
+	types[m.typ] = true
+	defer delete(types, m.typ)
+	for _, f := range m.fields {
+		column := prefix + f.column
+		if present[column] {
+			mapped[column] = true
+		} else {
+			e.UnfilledFields = append(e.UnfilledFields, fmt.Sprintf("%s(%s)", f.name, column))
+		}
+	}
+	if !collections {
+		return
+	}
+	for _, coll := range m.collections {
+		if !types[coll.elem] {
+			getStructMeta(coll.elem).checkMapping(prefix+coll.prefix, present, mapped, e, types, true)
+		}
+	}
+}
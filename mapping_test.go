@@ -0,0 +1,292 @@
+package trysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tagAddress struct {
+	City   string
+	Street string `colname:"street_name"`
+}
+
+type tagCustomer struct {
+	ID         int64  `colname:"id,pk,auto,table=customer"`
+	Name       string `colname:"name,omitempty"`
+	Secret     string `colname:"-"`
+	Version    int    `colname:"version,readonly"`
+	tagAddress `colname:",prefix=addr_"`
+}
+
+func TestParseColTag(t *testing.T) {
+	tag := parseColTag("id,pk,auto,table=customer")
+	if tag.name != "id" || !tag.pk || !tag.auto || tag.table != "customer" {
+		t.Errorf("tag = %+v", tag)
+	}
+	if tag := parseColTag("-"); !tag.ignore {
+		t.Errorf("tag = %+v", tag)
+	}
+	if tag := parseColTag(",prefix=addr_"); tag.name != "" || tag.prefix != "addr_" {
+		t.Errorf("tag = %+v", tag)
+	}
+	if tag := parseColTag("status,default=new"); !tag.hasDefault || tag.defaultValue != "new" {
+		t.Errorf("tag = %+v", tag)
+	}
+}
+
+type defaultOrder struct {
+	ID     int64   `colname:"id,pk,auto,table=orders"`
+	Status string  `colname:"status,default=new"`
+	Qty    int32   `colname:"qty,default=1"`
+	Rate   *uint16 `colname:"rate,default=5"`
+}
+
+func TestRepositoryDefault(t *testing.T) {
+	db, fake := newFakeDB()
+	repo := NewRepository[defaultOrder](NewSqlSessionFactory(Mysql, db, time.Second, false))
+	if err := repo.Insert(context.Background(), &defaultOrder{Qty: 3}); err != nil {
+		t.Fatal(err)
+	}
+	exec := fake.executed()[0]
+	if got := fmt.Sprint(exec.args); got != "[new 3 5]" {
+		t.Errorf("args = %v, query = %q", got, exec.query)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid default should panic")
+		}
+	}()
+	newStructMeta(reflect.TypeOf(struct {
+		Qty int `colname:"qty,default=x"`
+	}{}))
+}
+
+func TestStructMeta(t *testing.T) {
+	meta := newStructMeta(reflect.TypeOf(tagCustomer{}))
+	if meta.table != "customer" {
+		t.Errorf("table = %v", meta.table)
+	}
+	if got := fmt.Sprint(meta.columns()); got != "[id name version addr_city addr_street_name]" {
+		t.Errorf("columns = %v", got)
+	}
+	if len(meta.pks) != 1 || meta.auto != meta.pks[0] {
+		t.Errorf("pks = %v, auto = %v", meta.pks, meta.auto)
+	}
+}
+
+func TestScanWithTagOptions(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "name", "secret", "addr_city", "addr_street_name"},
+		[]driver.Value{int64(1), "a", "s", "Paris", "Rue"})
+	var customers []tagCustomer
+	err := NewMySqlSession(NewTxSession(db, false)).Select("*").From("customer").AsList(&customers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := customers[0]
+	if c.ID != 1 || c.Name != "a" || c.Secret != "" || c.City != "Paris" || c.Street != "Rue" {
+		t.Errorf("customer = %+v", c)
+	}
+}
+
+func TestRepositoryOmitEmpty(t *testing.T) {
+	db, fake := newFakeDB()
+	repo := NewRepository[tagCustomer](NewSqlSessionFactory(Mysql, db, time.Second, false))
+	customer := tagCustomer{ID: 3, Version: 2, tagAddress: tagAddress{City: "Paris"}}
+	if _, err := repo.UpdateByPK(context.Background(), &customer); err != nil {
+		t.Fatal(err)
+	}
+	want := "UPDATE customer\nSET addr_city = ?, addr_street_name = ?\nWHERE (id = ?) "
+	if got := fake.executed()[0].query; got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+}
+
+type benchRow struct {
+	ID         int64 `colname:"id"`
+	TenantID   int64
+	OrderNo    string
+	Customer   string
+	Phone      string
+	Email      string
+	Country    string
+	Province   string
+	City       string
+	Street     string
+	Zip        string
+	Amount     float64
+	Tax        float64
+	Discount   float64
+	Status     int
+	Remark     string
+	Creator    string
+	Modifier   string
+	CreateTime time.Time
+	UpdateTime time.Time
+}
+
+var benchColumns = []string{"id", "tenant_id", "order_no", "customer", "phone", "email", "country", "province",
+	"city", "street", "zip", "amount", "tax", "discount", "status", "remark", "creator", "modifier",
+	"create_time", "update_time"}
+
+// legacyScanDest 逐行比较每个字段和每一列，用作基准
+func legacyScanDest(value reflect.Value, columns []string) []any {
+	dest := make([]any, len(columns))
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fieldName, _ := columnName(typ.Field(i))
+		for index, name := range columns {
+			if name == fieldName {
+				dest[index] = value.Field(i).Addr().Interface()
+			}
+		}
+	}
+	return dest
+}
+
+func BenchmarkScanDest_Legacy(b *testing.B) {
+	typ := reflect.TypeOf(benchRow{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyScanDest(reflect.New(typ).Elem(), benchColumns)
+	}
+}
+
+func BenchmarkScanDest_Plan(b *testing.B) {
+	typ := reflect.TypeOf(benchRow{})
+	plan := getScanPlan(typ, benchColumns, scanOptions{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		plan.scanDest(reflect.New(typ).Elem())
+	}
+}
+
+func BenchmarkAsList(b *testing.B) {
+	row := make([]driver.Value, len(benchColumns))
+	for i := range row {
+		row[i] = "1"
+	}
+	row[0], row[18], row[19] = int64(1), time.Now(), time.Now()
+	rows := make([][]driver.Value, 1000)
+	for i := range rows {
+		rows[i] = row
+	}
+	db, fake := newFakeDB()
+	session := NewMySqlSession(NewTxSession(db, false))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fake.addResult(benchColumns, rows...)
+		var list []benchRow
+		if err := session.Select("*").From("orders").AsList(&list); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type nestedCustomer struct {
+	ID   int64
+	Name string
+}
+
+type nestedOrder struct {
+	ID       int64
+	Customer *nestedCustomer
+	Shipping tagAddress `colname:",prefix=ship_"`
+}
+
+func TestScanNestedStruct(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "customer__id", "customer__name", "ship_city"},
+		[]driver.Value{int64(1), int64(10), "Tom", "Paris"},
+		[]driver.Value{int64(2), nil, nil, "Rome"})
+	var orders []*nestedOrder
+	err := NewMySqlSession(NewTxSession(db, false)).Select("*").From("orders").AsList(&orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := orders[0].Customer; c == nil || c.ID != 10 || c.Name != "Tom" || orders[0].Shipping.City != "Paris" {
+		t.Errorf("orders[0] = %+v, customer = %+v", orders[0], c)
+	}
+	if orders[1].Customer != nil || orders[1].Shipping.City != "Rome" {
+		t.Errorf("orders[1] = %+v", orders[1])
+	}
+	if got := fmt.Sprint(getStructMeta(reflect.TypeOf(nestedOrder{})).columns()); got != "[id]" {
+		t.Errorf("writable columns = %v", got)
+	}
+}
+
+func TestMappingMode(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false, WithMappingMode(MappingStrict))
+
+	fake.addResult([]string{"id", "nme", "id"}, []driver.Value{int64(1), "a", int64(1)})
+	var users []genericUser
+	err := ssf.NewSqlSession().Select("*").From("user").AsList(&users)
+	mappingErr, ok := err.(*MappingError)
+	if !ok {
+		t.Fatalf("err = %v", err)
+	}
+	if fmt.Sprint(mappingErr.UnmappedColumns, mappingErr.UnfilledFields, mappingErr.DuplicateColumns) != "[nme] [Name(name)] [id]" {
+		t.Errorf("err = %v", mappingErr)
+	}
+
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(1), "a"})
+	var user genericUser
+	if err := ssf.NewSqlSession().Select("*").From("user").AsSingle(&user); err != nil || user.Name != "a" {
+		t.Errorf("user = %+v, err = %v", user, err)
+	}
+
+	// 组装时检查集合字段的列
+	fake.addResult([]string{"id", "order_no", "items__id", "items__sku", "items__opt__name", "items__price"})
+	var orders []assemblyOrder
+	err = ssf.NewSqlSession().Select("*").From("orders").AsList(&orders)
+	if err == nil || err.Error() != "mapping trysql.assemblyOrder: unmapped columns [items__price]" {
+		t.Errorf("err = %v", err)
+	}
+
+	fake.addResult([]string{"id", "nme"}, []driver.Value{int64(1), "a"})
+	users = nil
+	if err := ssf.NewSqlSession().Mapping(MappingWarn).Select("*").From("user").AsList(&users); err != nil || len(users) != 1 {
+		t.Errorf("users = %+v, err = %v", users, err)
+	}
+}
+
+func TestScanPlanCacheBounded(t *testing.T) {
+	type row struct {
+		Id   int64
+		Name string
+	}
+	typ := reflect.TypeOf(row{})
+	cache := newScanPlanCache[*scanPlan](2)
+	for i, columns := range [][]string{{"id"}, {"name"}, {"id", "name"}} {
+		key := scanPlanKey{typ: typ, columns: strings.Join(columns, "\x00")}
+		cache.loadOrStore(key, newScanPlan(getStructMeta(typ), columns, false, scanOptions{}))
+		if i == 1 {
+			// 访问 "id" 后, "name" 成为最久未使用的
+			cache.load(scanPlanKey{typ: typ, columns: "id"})
+		}
+	}
+	if cache.len() != 2 {
+		t.Fatalf("len = %v", cache.len())
+	}
+	if _, ok := cache.load(scanPlanKey{typ: typ, columns: "name"}); ok {
+		t.Error("expected least recently used plan to be evicted")
+	}
+	if _, ok := cache.load(scanPlanKey{typ: typ, columns: "id"}); !ok {
+		t.Error("expected recently used plan to be kept")
+	}
+
+	// 注册 Converter 后使用新的 converterSet, 旧的 *scanPlan 不再命中
+	registry := NewConverterRegistry()
+	before := getScanPlan(typ, []string{"id"}, scanOptions{converters: registry.snapshot()})
+	registry.Register(reflect.TypeOf(""), nil, nil)
+	if after := getScanPlan(typ, []string{"id"}, scanOptions{converters: registry.snapshot()}); after == before {
+		t.Error("expected a new plan after Register")
+	}
+}
@@ -2,7 +2,9 @@ package trysql
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -11,7 +13,11 @@ type MySqlSession struct {
 }
 
 func NewMySqlSession(dbSession DbSession) SqlSession {
-	sqlBuilder := newBaseSqlSession(dbSession)
+	return newMySqlSession(dbSession, defaultSessionOptions())
+}
+
+func newMySqlSession(dbSession DbSession, options sessionOptions) *MySqlSession {
+	sqlBuilder := newBaseSqlSession(dbSession, Mysql, options)
 	return &MySqlSession{sqlBuilder}
 }
 func (sb *MySqlSession) Select(columns ...string) SqlSession {
@@ -212,6 +218,7 @@ func (sb *MySqlSession) DoneInsertIdContext(ctx context.Context, _ string) (int6
 	if sb.logSql {
 		logSql(sqlText, args)
 	}
+	sb.Reset()
 	result, err := sb.baseSqlSession.ExecContext(ctx, sqlText, args...)
 	if err != nil {
 		return 0, err
@@ -232,6 +239,38 @@ func (sb *MySqlSession) DoneRowsAffected() (int64, error) {
 	return sb.DoneRowsAffectedContext(context.Background())
 }
 
+func (sb *MySqlSession) DoneBatchInsertContext(ctx context.Context, opts BatchInsertOptions) (int64, error) {
+	return sb.baseSqlSession.DoneBatchInsertContext(ctx, opts, func() SqlSession {
+		return newMySqlSession(sb.dbSession, sb.options)
+	})
+}
+
+func (sb *MySqlSession) DoneBatchInsert(opts BatchInsertOptions) (int64, error) {
+	return sb.DoneBatchInsertContext(context.Background(), opts)
+}
+
+func (sb *MySqlSession) AddBatch() SqlSession {
+	sqlText, args := sb.builderSQLText()
+	sb.addBatch(sqlText, args)
+	return sb
+}
+
+func (sb *MySqlSession) ExecuteBatchContext(ctx context.Context) ([]BatchResult, error) {
+	return sb.baseSqlSession.ExecuteBatchContext(ctx)
+}
+
+func (sb *MySqlSession) ExecuteBatch() ([]BatchResult, error) {
+	return sb.ExecuteBatchContext(context.Background())
+}
+
+func (sb *MySqlSession) CallContext(ctx context.Context, proc string, args ...any) error {
+	return sb.baseSqlSession.CallContext(ctx, proc, args)
+}
+
+func (sb *MySqlSession) Call(proc string, args ...any) error {
+	return sb.CallContext(context.Background(), proc, args...)
+}
+
 func (sb *MySqlSession) AsSingleContext(ctx context.Context, dest any) error {
 	sqlText, args := sb.builderSQLText()
 	return sb.baseSqlSession.AsSingleContext(ctx, sqlText, args, dest)
@@ -241,6 +280,29 @@ func (sb *MySqlSession) AsSingle(dest any) error {
 	return sb.AsSingleContext(context.Background(), dest)
 }
 
+func (sb *MySqlSession) AsSingleOKContext(ctx context.Context, dest any) (bool, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsSingleOKContext(ctx, sqlText, args, dest)
+}
+
+func (sb *MySqlSession) AsSingleOK(dest any) (bool, error) {
+	return sb.AsSingleOKContext(context.Background(), dest)
+}
+
+func (sb *MySqlSession) AsOneContext(ctx context.Context, dest any) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsOneContext(ctx, sqlText, args, dest)
+}
+
+func (sb *MySqlSession) AsOne(dest any) error {
+	return sb.AsOneContext(context.Background(), dest)
+}
+
+func (sb *MySqlSession) queryContext(ctx context.Context) (*sql.Rows, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.queryContext(ctx, sqlText, args)
+}
+
 func (sb *MySqlSession) AsListContext(ctx context.Context, dest any) error {
 	sqlText, args := sb.builderSQLText()
 	return sb.baseSqlSession.AsListContext(ctx, sqlText, args, dest)
@@ -250,6 +312,15 @@ func (sb *MySqlSession) AsList(dest any) error {
 	return sb.AsListContext(context.Background(), dest)
 }
 
+func (sb *MySqlSession) AsMultiListContext(ctx context.Context, dests ...any) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsMultiListContext(ctx, sqlText, args, dests...)
+}
+
+func (sb *MySqlSession) AsMultiList(dests ...any) error {
+	return sb.AsMultiListContext(context.Background(), dests...)
+}
+
 func (sb *MySqlSession) AsPrimitiveContext(ctx context.Context, dest any) error {
 
 	sqlText, args := sb.builderSQLText()
@@ -280,6 +351,45 @@ func (sb *MySqlSession) AsMapContext(ctx context.Context) (map[string]any, error
 func (sb *MySqlSession) AsMap() (map[string]any, error) {
 	return sb.AsMapContext(context.Background())
 }
+func (sb *MySqlSession) AsMapOKContext(ctx context.Context) (map[string]any, bool, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsMapOKContext(ctx, sqlText, args)
+}
+func (sb *MySqlSession) AsMapOK() (map[string]any, bool, error) {
+	return sb.AsMapOKContext(context.Background())
+}
+func (sb *MySqlSession) AsTableContext(ctx context.Context) (*Table, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsTableContext(ctx, sqlText, args)
+}
+func (sb *MySqlSession) AsTable() (*Table, error) {
+	return sb.AsTableContext(context.Background())
+}
+func (sb *MySqlSession) ExportCSVContext(ctx context.Context, w io.Writer, opts CSVOptions) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.ExportCSVContext(ctx, sqlText, args, w, opts)
+}
+func (sb *MySqlSession) ExportCSV(w io.Writer, opts CSVOptions) error {
+	return sb.ExportCSVContext(context.Background(), w, opts)
+}
+func (sb *MySqlSession) ExportJSONLinesContext(ctx context.Context, w io.Writer) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.ExportJSONLinesContext(ctx, sqlText, args, w)
+}
+func (sb *MySqlSession) ExportJSONLines(w io.Writer) error {
+	return sb.ExportJSONLinesContext(context.Background(), w)
+}
+
+func (sb *MySqlSession) Build() (string, []any, error) {
+	if err := sb.checkParams(); err != nil {
+		return "", nil, err
+	}
+	sqlText, args := sb.builderSQLText()
+	if err := checkArgs(args); err != nil {
+		return "", nil, err
+	}
+	return sqlText, args, nil
+}
 
 func (sb *MySqlSession) Reset() SqlSession {
 	sb.baseSqlSession.Reset()
@@ -287,7 +397,7 @@ func (sb *MySqlSession) Reset() SqlSession {
 }
 
 func (sb *MySqlSession) New() SqlSession {
-	return NewMySqlSession(sb.dbSession)
+	return newMySqlSession(sb.dbSession, sb.options)
 }
 
 func (sb *MySqlSession) LogSql(logSql bool) SqlSession {
@@ -295,6 +405,11 @@ func (sb *MySqlSession) LogSql(logSql bool) SqlSession {
 	return sb
 }
 
+func (sb *MySqlSession) Mapping(mode MappingMode) SqlSession {
+	sb.baseSqlSession.options.mappingMode = mode
+	return sb
+}
+
 func (sb *MySqlSession) builderSQLText() (string, []any) {
 	var sqlText = sb.getSqlText()
 	dynamicPlaceholders, injectedPlaceholders := getDynamicAndInjectedPlaceholders(sqlText)
@@ -309,5 +424,5 @@ func (sb *MySqlSession) builderSQLText() (string, []any) {
 		sqlText = strings.Replace(sqlText, value, fmt.Sprintf("%v", injected), 1)
 	}
 
-	return sqlText, args
+	return sqlText, sb.bindArgs(args)
 }
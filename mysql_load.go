@@ -0,0 +1,180 @@
+package trysql
+
+import (
+	"bufio"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// LoadDataOptions LoadData 的数据格式, 未指定的选项使用默认值
+type LoadDataOptions struct {
+	// FieldsTerminatedBy 字段分隔符, 默认为 ","
+	FieldsTerminatedBy string
+	// FieldsEnclosedBy 字段的引号, 默认不使用引号
+	FieldsEnclosedBy string
+	// NoEscape 使用 ESCAPED BY '', 字段中的反斜杠不作为转义字符
+	NoEscape bool
+	// LinesTerminatedBy 行分隔符, 默认为 "\n"
+	LinesTerminatedBy string
+	// IgnoreLines 忽略开头的行数, 如 CSV 的标题行
+	IgnoreLines int
+}
+
+// loadDataSeq 用于生成唯一的 Reader Handler 名称
+var loadDataSeq uint64
+
+// LoadDataContext 使用 LOAD DATA LOCAL INFILE 将 reader 中的数据批量写入 table, 返回写入的记录数。
+//
+// 每次调用注册一个唯一的 Reader Handler, 执行完成后注销
+func (sb *MySqlSession) LoadDataContext(ctx context.Context, table string, columns []string, reader io.Reader, opts LoadDataOptions) (int64, error) {
+	name := fmt.Sprintf("trysql_%d", atomic.AddUint64(&loadDataSeq, 1))
+	mysql.RegisterReaderHandler(name, func() io.Reader { return reader })
+	defer mysql.DeregisterReaderHandler(name)
+
+	sqlText := loadDataSQL(name, table, columns, opts)
+	if sb.logSql {
+		logSql(sqlText, nil)
+	}
+	sb.Reset()
+	result, err := execUncached(ctx, sb.dbSession, sqlText)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// LoadData 使用 LOAD DATA LOCAL INFILE 将 reader 中的数据批量写入 table, 返回写入的记录数
+func (sb *MySqlSession) LoadData(table string, columns []string, reader io.Reader, opts LoadDataOptions) (int64, error) {
+	return sb.LoadDataContext(context.Background(), table, columns, reader, opts)
+}
+
+// LoadDataRowsContext 将 source 以 CSV 格式流式写入 LOAD DATA LOCAL INFILE, 返回写入的记录数。
+//
+// source 可以是元素为 struct 或 struct 指针的 slice, [][]any 或者 CopyRowFunc,
+// source 为 struct slice 且 columns 为空时, 使用 struct 映射的可写入列
+func (sb *MySqlSession) LoadDataRowsContext(ctx context.Context, table string, columns []string, source any) (int64, error) {
+	emitRows, columns, err := copySource(columns, source)
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("load data into %s: columns must be specified", table)
+	}
+	pr, pw := io.Pipe()
+	// LOAD DATA 提前结束时, 停止写入
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(sb.writeLoadDataCSV(pw, len(columns), emitRows))
+	}()
+	return sb.LoadDataContext(ctx, table, columns, pr, LoadDataOptions{FieldsEnclosedBy: `"`, NoEscape: true})
+}
+
+// LoadDataRows 将 source 以 CSV 格式流式写入 LOAD DATA LOCAL INFILE, 返回写入的记录数
+func (sb *MySqlSession) LoadDataRows(table string, columns []string, source any) (int64, error) {
+	return sb.LoadDataRowsContext(context.Background(), table, columns, source)
+}
+
+// writeLoadDataCSV 将 emitRows 产生的记录写入 w, 非 NULL 值都使用双引号, NULL 写入不带引号的 NULL
+func (sb *MySqlSession) writeLoadDataCSV(w io.Writer, columns int, emitRows CopyRowFunc) error {
+	bw := bufio.NewWriter(w)
+	err := emitRows(func(values ...any) error {
+		if len(values) != columns {
+			return fmt.Errorf("expected %d values, but %d", columns, len(values))
+		}
+		for i, arg := range sb.bindArgs(append([]any(nil), values...)) {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			text, null, err := loadDataValue(arg)
+			if err != nil {
+				return err
+			}
+			if null {
+				bw.WriteString("NULL")
+				continue
+			}
+			bw.WriteByte('"')
+			bw.WriteString(strings.ReplaceAll(text, `"`, `""`))
+			bw.WriteByte('"')
+		}
+		_, err := bw.WriteString("\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// loadDataValue 将参数值格式化为 LOAD DATA 的字段文本
+func loadDataValue(value any) (string, bool, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", false, err
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", true, nil
+	case string:
+		return v, false, nil
+	case []byte:
+		return string(v), false, nil
+	case bool:
+		if v {
+			return "1", false, nil
+		}
+		return "0", false, nil
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999"), false, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), false, nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), false, nil
+	default:
+		return fmt.Sprint(v), false, nil
+	}
+}
+
+// loadDataSQL 生成 LOAD DATA LOCAL INFILE 语句
+func loadDataSQL(name string, table string, columns []string, opts LoadDataOptions) string {
+	if opts.FieldsTerminatedBy == "" {
+		opts.FieldsTerminatedBy = ","
+	}
+	if opts.LinesTerminatedBy == "" {
+		opts.LinesTerminatedBy = "\n"
+	}
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s", name, table))
+	b.WriteString(" FIELDS TERMINATED BY " + quoteLoadData(opts.FieldsTerminatedBy))
+	if opts.FieldsEnclosedBy != "" {
+		b.WriteString(" ENCLOSED BY " + quoteLoadData(opts.FieldsEnclosedBy))
+	}
+	if opts.NoEscape {
+		b.WriteString(" ESCAPED BY ''")
+	}
+	b.WriteString(" LINES TERMINATED BY " + quoteLoadData(opts.LinesTerminatedBy))
+	if opts.IgnoreLines > 0 {
+		b.WriteString(fmt.Sprintf(" IGNORE %d LINES", opts.IgnoreLines))
+	}
+	if len(columns) > 0 {
+		b.WriteString(" (" + strings.Join(columns, ", ") + ")")
+	}
+	return b.String()
+}
+
+// quoteLoadData 将 s 转换为 MySQL 字符串字面量
+func quoteLoadData(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return "'" + r.Replace(s) + "'"
+}
@@ -0,0 +1,40 @@
+package trysql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadDataSQL(t *testing.T) {
+	got := loadDataSQL("r1", "orders", []string{"id", "name"}, LoadDataOptions{FieldsTerminatedBy: "\t", IgnoreLines: 1})
+	want := `LOAD DATA LOCAL INFILE 'Reader::r1' INTO TABLE orders FIELDS TERMINATED BY '\t' LINES TERMINATED BY '\n' IGNORE 1 LINES (id, name)`
+	if got != want {
+		t.Errorf("sql = %s, want %s", got, want)
+	}
+}
+
+func TestLoadDataRows(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.rowsAffected = 2
+	session := newMySqlSession(NewTxSession(db, false), defaultSessionOptions())
+
+	orders := []repoOrder{{OrderNo: `A"1`, Amount: 1.5}, {OrderNo: `B\2`, Amount: 2}}
+	n, err := session.LoadDataRows("orders", nil, orders)
+	if err != nil || n != 2 {
+		t.Fatalf("n = %v, err = %v", n, err)
+	}
+	query := fake.executed()[0].query
+	if !strings.HasPrefix(query, "LOAD DATA LOCAL INFILE 'Reader::trysql_") ||
+		!strings.HasSuffix(query, `ENCLOSED BY '"' ESCAPED BY '' LINES TERMINATED BY '\n' (order_no, amount)`) {
+		t.Errorf("query = %s", query)
+	}
+
+	b := strings.Builder{}
+	emitRows, _, _ := copySource([]string{"id", "name", "ok"}, [][]any{{1, `a"b`, true}, {2, nil, false}})
+	if err := session.writeLoadDataCSV(&b, 3, emitRows); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"1\",\"a\"\"b\",\"1\"\n\"2\",NULL,\"0\"\n"; b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+}
@@ -211,3 +211,31 @@ func initDB() (*sql.DB, error) {
 
 	return open, err
 }
+
+func Test_MYSQL_Build(t *testing.T) {
+	session := NewMySqlSession(nil).Select("id", "tracking_no").
+		From("acc_tracking_result").
+		Where("id > #{id}", 10).
+		WhereIn("carrier_id", []any{1, 2}).
+		Limit(5)
+	sqlText, args, err := session.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT id, tracking_no\nFROM acc_tracking_result\nWHERE (id > ? AND carrier_id IN (?,?)) LIMIT ? "
+	if sqlText != want {
+		t.Errorf("sql = %q, want %q", sqlText, want)
+	}
+	if fmt.Sprint(args) != "[10 1 2 5]" {
+		t.Errorf("args = %v", args)
+	}
+	// Build 不重置 SqlSession
+	if again, _, _ := session.Build(); again != sqlText {
+		t.Errorf("session was reset by Build")
+	}
+
+	_, _, err = NewMySqlSession(nil).Select("id").From("t").Where("id = #{id}").Build()
+	if err == nil {
+		t.Error("expected error for missing parameter value")
+	}
+}
@@ -2,7 +2,9 @@ package trysql
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
@@ -12,7 +14,11 @@ type PostgreSqlSession struct {
 }
 
 func NewPostgreSqlSession(dbSession DbSession) SqlSession {
-	sqlBuilder := newBaseSqlSession(dbSession)
+	return newPostgreSqlSession(dbSession, defaultSessionOptions())
+}
+
+func newPostgreSqlSession(dbSession DbSession, options sessionOptions) *PostgreSqlSession {
+	sqlBuilder := newBaseSqlSession(dbSession, Postgresql, options)
 	return &PostgreSqlSession{sqlBuilder}
 }
 
@@ -217,6 +223,7 @@ func (sb *PostgreSqlSession) DoneInsertIdContext(ctx context.Context, column str
 	if sb.logSql {
 		logSql(sqlText, args)
 	}
+	sb.Reset()
 	var id int64
 	err := sb.baseSqlSession.dbSession.QueryRowContext(ctx, sqlText, args...).Scan(&id)
 	if err != nil {
@@ -238,6 +245,38 @@ func (sb *PostgreSqlSession) DoneRowsAffected() (int64, error) {
 	return sb.DoneRowsAffectedContext(context.Background())
 }
 
+func (sb *PostgreSqlSession) DoneBatchInsertContext(ctx context.Context, opts BatchInsertOptions) (int64, error) {
+	return sb.baseSqlSession.DoneBatchInsertContext(ctx, opts, func() SqlSession {
+		return newPostgreSqlSession(sb.dbSession, sb.options)
+	})
+}
+
+func (sb *PostgreSqlSession) DoneBatchInsert(opts BatchInsertOptions) (int64, error) {
+	return sb.DoneBatchInsertContext(context.Background(), opts)
+}
+
+func (sb *PostgreSqlSession) AddBatch() SqlSession {
+	sqlText, args := sb.builderSQLText()
+	sb.addBatch(sqlText, args)
+	return sb
+}
+
+func (sb *PostgreSqlSession) ExecuteBatchContext(ctx context.Context) ([]BatchResult, error) {
+	return sb.baseSqlSession.ExecuteBatchContext(ctx)
+}
+
+func (sb *PostgreSqlSession) ExecuteBatch() ([]BatchResult, error) {
+	return sb.ExecuteBatchContext(context.Background())
+}
+
+func (sb *PostgreSqlSession) CallContext(ctx context.Context, proc string, args ...any) error {
+	return sb.baseSqlSession.CallContext(ctx, proc, args)
+}
+
+func (sb *PostgreSqlSession) Call(proc string, args ...any) error {
+	return sb.CallContext(context.Background(), proc, args...)
+}
+
 func (sb *PostgreSqlSession) AsSingleContext(ctx context.Context, dest any) error {
 	sqlText, args := sb.builderSQLText()
 	return sb.baseSqlSession.AsSingleContext(ctx, sqlText, args, dest)
@@ -247,6 +286,29 @@ func (sb *PostgreSqlSession) AsSingle(dest any) error {
 	return sb.AsSingleContext(context.Background(), dest)
 }
 
+func (sb *PostgreSqlSession) AsSingleOKContext(ctx context.Context, dest any) (bool, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsSingleOKContext(ctx, sqlText, args, dest)
+}
+
+func (sb *PostgreSqlSession) AsSingleOK(dest any) (bool, error) {
+	return sb.AsSingleOKContext(context.Background(), dest)
+}
+
+func (sb *PostgreSqlSession) AsOneContext(ctx context.Context, dest any) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsOneContext(ctx, sqlText, args, dest)
+}
+
+func (sb *PostgreSqlSession) AsOne(dest any) error {
+	return sb.AsOneContext(context.Background(), dest)
+}
+
+func (sb *PostgreSqlSession) queryContext(ctx context.Context) (*sql.Rows, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.queryContext(ctx, sqlText, args)
+}
+
 func (sb *PostgreSqlSession) AsListContext(ctx context.Context, dest any) error {
 	sqlText, args := sb.builderSQLText()
 	return sb.baseSqlSession.AsListContext(ctx, sqlText, args, dest)
@@ -256,6 +318,15 @@ func (sb *PostgreSqlSession) AsList(dest any) error {
 	return sb.AsListContext(context.Background(), dest)
 }
 
+func (sb *PostgreSqlSession) AsMultiListContext(ctx context.Context, dests ...any) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsMultiListContext(ctx, sqlText, args, dests...)
+}
+
+func (sb *PostgreSqlSession) AsMultiList(dests ...any) error {
+	return sb.AsMultiListContext(context.Background(), dests...)
+}
+
 func (sb *PostgreSqlSession) AsPrimitiveContext(ctx context.Context, dest any) error {
 	sqlText, args := sb.builderSQLText()
 	return sb.baseSqlSession.AsPrimitiveContext(ctx, sqlText, args, dest)
@@ -285,8 +356,47 @@ func (sb *PostgreSqlSession) AsMapContext(ctx context.Context) (map[string]any,
 func (sb *PostgreSqlSession) AsMap() (map[string]any, error) {
 	return sb.AsMapContext(context.Background())
 }
+func (sb *PostgreSqlSession) AsMapOKContext(ctx context.Context) (map[string]any, bool, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsMapOKContext(ctx, sqlText, args)
+}
+func (sb *PostgreSqlSession) AsMapOK() (map[string]any, bool, error) {
+	return sb.AsMapOKContext(context.Background())
+}
+func (sb *PostgreSqlSession) AsTableContext(ctx context.Context) (*Table, error) {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.AsTableContext(ctx, sqlText, args)
+}
+func (sb *PostgreSqlSession) AsTable() (*Table, error) {
+	return sb.AsTableContext(context.Background())
+}
+func (sb *PostgreSqlSession) ExportCSVContext(ctx context.Context, w io.Writer, opts CSVOptions) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.ExportCSVContext(ctx, sqlText, args, w, opts)
+}
+func (sb *PostgreSqlSession) ExportCSV(w io.Writer, opts CSVOptions) error {
+	return sb.ExportCSVContext(context.Background(), w, opts)
+}
+func (sb *PostgreSqlSession) ExportJSONLinesContext(ctx context.Context, w io.Writer) error {
+	sqlText, args := sb.builderSQLText()
+	return sb.baseSqlSession.ExportJSONLinesContext(ctx, sqlText, args, w)
+}
+func (sb *PostgreSqlSession) ExportJSONLines(w io.Writer) error {
+	return sb.ExportJSONLinesContext(context.Background(), w)
+}
 func (sb *PostgreSqlSession) InTx(txFunc func() error) error {
-	return sb.InTx(txFunc)
+	return sb.baseSqlSession.InTx(txFunc)
+}
+
+func (sb *PostgreSqlSession) Build() (string, []any, error) {
+	if err := sb.checkParams(); err != nil {
+		return "", nil, err
+	}
+	sqlText, args := sb.builderSQLText()
+	if err := checkArgs(args); err != nil {
+		return "", nil, err
+	}
+	return sqlText, args, nil
 }
 
 func (sb *PostgreSqlSession) Reset() SqlSession {
@@ -295,7 +405,7 @@ func (sb *PostgreSqlSession) Reset() SqlSession {
 }
 
 func (sb *PostgreSqlSession) New() SqlSession {
-	return NewPostgreSqlSession(sb.dbSession)
+	return newPostgreSqlSession(sb.dbSession, sb.options)
 }
 
 func (sb *PostgreSqlSession) LogSql(logSql bool) SqlSession {
@@ -303,6 +413,11 @@ func (sb *PostgreSqlSession) LogSql(logSql bool) SqlSession {
 	return sb
 }
 
+func (sb *PostgreSqlSession) Mapping(mode MappingMode) SqlSession {
+	sb.baseSqlSession.options.mappingMode = mode
+	return sb
+}
+
 func (sb *PostgreSqlSession) builderSQLText() (string, []any) {
 	var sqlText = sb.getSqlText()
 	dynamicPlaceholders, injectedPlaceholders := getDynamicAndInjectedPlaceholders(sqlText)
@@ -315,5 +430,9 @@ func (sb *PostgreSqlSession) builderSQLText() (string, []any) {
 		injected := sb.argMap[value]
 		sqlText = strings.Replace(sqlText, value, fmt.Sprintf("%v", injected), 1)
 	}
+	args = sb.bindArgs(args)
+	for index, arg := range args {
+		args[index] = bindArray(arg)
+	}
 	return sqlText, args
 }
@@ -0,0 +1,184 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// CopyRowFunc 逐条产生 COPY FROM 数据的回调, 每次调用 emit 写入一条记录, 返回错误时终止 COPY
+//
+//	trysql.CopyRowFunc(func(emit func(values ...any) error) error {
+//		for scanner.Scan() {
+//			if err := emit(parse(scanner.Text())...); err != nil {
+//				return err
+//			}
+//		}
+//		return scanner.Err()
+//	})
+type CopyRowFunc func(emit func(values ...any) error) error
+
+// CopyFromContext 使用 COPY FROM STDIN 将 source 批量写入 table(可以是 schema.table), 返回写入的记录数。
+//
+// source 可以是元素为 struct 或 struct 指针的 slice, [][]any 或者 CopyRowFunc,
+// source 为 struct slice 且 columns 为空时, 使用 struct 映射的可写入列。
+// 当前 SqlSession 是事务性的时在该事务中执行, 否则在新的事务中执行
+func (sb *PostgreSqlSession) CopyFromContext(ctx context.Context, table string, columns []string, source any) (int64, error) {
+	emitRows, columns, err := copySource(columns, source)
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("copy into %s: columns must be specified", table)
+	}
+	if sb.logSql {
+		logSql(copyInQuery(table, columns), nil)
+	}
+	sb.Reset()
+
+	switch dbSession := sb.dbSession.(type) {
+	case *TxDbSession:
+		return sb.copyIn(ctx, dbSession.DB, table, columns, emitRows)
+	case *NonTxDbSession:
+		tx, err := dbSession.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		n, err := sb.copyIn(ctx, tx, table, columns, emitRows)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+		return n, tx.Commit()
+	default:
+		return 0, fmt.Errorf("unsupported DbSession %T", sb.dbSession)
+	}
+}
+
+// CopyFrom 使用 COPY FROM STDIN 将 source 批量写入 table, 返回写入的记录数
+func (sb *PostgreSqlSession) CopyFrom(table string, columns []string, source any) (int64, error) {
+	return sb.CopyFromContext(context.Background(), table, columns, source)
+}
+
+// copyInQuery 生成 COPY FROM STDIN 语句, table 为 schema.table 时分别引用 schema 和表名
+func copyInQuery(table string, columns []string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pq.CopyInSchema(schema, name, columns...)
+	}
+	return pq.CopyIn(table, columns...)
+}
+
+func (sb *PostgreSqlSession) copyIn(ctx context.Context, tx *sql.Tx, table string, columns []string, emitRows CopyRowFunc) (n int64, err error) {
+	stmt, err := tx.PrepareContext(ctx, copyInQuery(table, columns))
+	if err != nil {
+		return 0, err
+	}
+	defer func(stmt *sql.Stmt) {
+		if closeErr := stmt.Close(); err == nil {
+			err = closeErr
+		}
+	}(stmt)
+	err = emitRows(func(values ...any) error {
+		if len(values) != len(columns) {
+			return fmt.Errorf("copy into %s: expected %d values, but %d", table, len(columns), len(values))
+		}
+		args := sb.bindArgs(append([]any(nil), values...))
+		for i, arg := range args {
+			args[i] = bindArray(arg)
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// 不带参数的 Exec 结束 COPY
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// copySource 将 source 转换为 CopyRowFunc, 同时返回 COPY 的列
+func copySource(columns []string, source any) (CopyRowFunc, []string, error) {
+	switch s := source.(type) {
+	case CopyRowFunc:
+		return s, columns, nil
+	case func(emit func(values ...any) error) error:
+		return s, columns, nil
+	case [][]any:
+		return func(emit func(values ...any) error) error {
+			for _, row := range s {
+				if err := emit(row...); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, columns, nil
+	}
+
+	rv := reflect.ValueOf(source)
+	if rv.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("unsupported copy source %T", source)
+	}
+	elemType := rv.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct || isPrimitiveType(elemType) {
+		return nil, nil, fmt.Errorf("unsupported copy source %T", source)
+	}
+	meta := getStructMeta(elemType)
+	var fields []*fieldMeta
+	if len(columns) == 0 {
+		for _, f := range meta.fields {
+			if !f.nested && !f.tag.auto && !f.tag.readonly {
+				fields = append(fields, f)
+				columns = append(columns, f.column)
+			}
+		}
+	} else {
+		for _, column := range columns {
+			f, ok := meta.byColumn[column]
+			if !ok || f.nested {
+				return nil, nil, fmt.Errorf("column %s is not mapped by %v", column, elemType)
+			}
+			fields = append(fields, f)
+		}
+	}
+	return func(emit func(values ...any) error) error {
+		values := make([]any, len(fields))
+		for i := 0; i < rv.Len(); i++ {
+			entity := reflect.Indirect(rv.Index(i))
+			if !entity.IsValid() {
+				return fmt.Errorf("copy source element %d is nil", i)
+			}
+			for j, f := range fields {
+				values[j] = f.copyValue(entity)
+			}
+			if err := emit(values...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, columns, nil
+}
+
+// copyValue 返回 COPY 时 entity 中该字段写入的值
+func (f *fieldMeta) copyValue(entity reflect.Value) any {
+	fv, ok := fieldValue(entity, f.index)
+	if !ok {
+		return nil
+	}
+	if f.tag.json {
+		return JSON(fv.Interface())
+	}
+	return fv.Interface()
+}
@@ -0,0 +1,66 @@
+package trysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCopyFrom(t *testing.T) {
+	db, fake := newFakeDB()
+	session := newPostgreSqlSession(NewTxSession(db, false), defaultSessionOptions())
+
+	orders := []*repoOrder{{OrderNo: "A", Amount: 1}, {OrderNo: "B", Amount: 2}}
+	n, err := session.CopyFrom("orders", nil, orders)
+	if err != nil || n != 2 {
+		t.Fatalf("n = %v, err = %v", n, err)
+	}
+	execs := fake.executed()
+	if len(execs) != 3 || execs[0].query != `COPY "orders" ("order_no", "amount") FROM STDIN` {
+		t.Fatalf("execs = %v", execs)
+	}
+	if execs[0].args[0] != "A" || execs[1].args[1] != 2.0 || len(execs[2].args) != 0 {
+		t.Errorf("execs = %v", execs)
+	}
+	if fake.begins != 1 || fake.commits != 1 {
+		t.Errorf("begins = %v, commits = %v", fake.begins, fake.commits)
+	}
+
+	n, err = session.CopyFrom("t", []string{"id", "tags"}, [][]any{{1, []string{"a"}}})
+	if err != nil || n != 1 || fake.executed()[3].args[1] != "{\"a\"}" {
+		t.Errorf("n = %v, err = %v, execs = %v", n, err, fake.executed())
+	}
+
+	n, err = session.CopyFrom("public.orders", []string{"id"}, [][]any{{1}})
+	if query := fake.executed()[5].query; err != nil || n != 1 || query != `COPY "public"."orders" ("id") FROM STDIN` {
+		t.Errorf("n = %v, err = %v, query = %v", n, err, query)
+	}
+}
+
+func TestCopyFromInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Postgresql, db, time.Second, false)
+	stop := errors.New("stop")
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		n, err := session.(*PostgreSqlSession).CopyFromContext(ctx, "t", []string{"id"},
+			CopyRowFunc(func(emit func(values ...any) error) error {
+				for i := 0; i < 3; i++ {
+					if err := emit(i); err != nil {
+						return err
+					}
+				}
+				return stop
+			}))
+		if n != 0 {
+			t.Errorf("n = %v", n)
+		}
+		return err
+	})
+	if err != stop {
+		t.Errorf("err = %v", err)
+	}
+	if fake.begins != 1 || fake.rollbacks != 1 || len(fake.executed()) != 3 {
+		t.Errorf("begins = %v, rollbacks = %v, execs = %v", fake.begins, fake.rollbacks, fake.executed())
+	}
+}
@@ -244,3 +244,23 @@ func initPostgresqlDB() {
 	}
 	enabledLogSql(true)
 }
+
+func Test_PG_Build(t *testing.T) {
+	session := NewPostgreSqlSession(nil).Update("acc_tracking_result").
+		Set("title", "hello").
+		Where("id = #{id}", 3)
+	sqlText, args, err := session.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "UPDATE acc_tracking_result\nSET title = $1\nWHERE (id = $2) "
+	if sqlText != want {
+		t.Errorf("sql = %q, want %q", sqlText, want)
+	}
+	if fmt.Sprint(args) != "[hello 3]" {
+		t.Errorf("args = %v", args)
+	}
+	if sets := session.SQL().Statement().Sets(); len(sets) != 1 || sets[0] != "title = #{title}" {
+		t.Errorf("sets = %v", sets)
+	}
+}
@@ -0,0 +1,129 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// callSession 执行存储过程调用的数据库连接, *sql.Conn, *sql.Tx 和 DbSession 都满足
+type callSession interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// CallContext 调用存储过程 proc, args 中的 sql.Out 参数在调用完成后写入 Dest。
+//
+// MySQL 和 PostgreSQL 的驱动都不支持 sql.Out: MySQL 通过会话变量传递 OUT 参数,
+// PostgreSQL 从 CALL 返回的记录中读取 OUT 参数。
+// 返回结果集的存储过程使用 AppendRaw("CALL ...") 和 AsMultiList 查询
+func (bss *baseSqlSession) CallContext(ctx context.Context, proc string, args []any) error {
+	bss.Reset()
+	for i, arg := range args {
+		if out, ok := arg.(sql.Out); ok {
+			if rv := reflect.ValueOf(out.Dest); rv.Kind() != reflect.Ptr || rv.IsNil() {
+				return fmt.Errorf("call %s: argument %d: sql.Out.Dest must be a non-nil pointer, but %T", proc, i, out.Dest)
+			}
+		}
+	}
+	if bss.dbType == Postgresql {
+		return bss.callPostgreSql(ctx, proc, args)
+	}
+	return bss.callMySql(ctx, proc, args)
+}
+
+func (bss *baseSqlSession) callMySql(ctx context.Context, proc string, args []any) error {
+	var session callSession = bss.dbSession
+	params := make([]string, len(args))
+	var callArgs, dests []any
+	var vars []string
+	for i, arg := range args {
+		out, ok := arg.(sql.Out)
+		if !ok {
+			params[i] = "?"
+			callArgs = append(callArgs, arg)
+			continue
+		}
+		if len(vars) == 0 {
+			// 会话变量只在同一连接中可见
+			if s, ok := bss.dbSession.(*NonTxDbSession); ok {
+				conn, err := s.DB.Conn(ctx)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				session = conn
+			}
+		}
+		params[i] = "@trysql_out_" + strconv.Itoa(i)
+		vars = append(vars, params[i])
+		dests = append(dests, out.Dest)
+		var in any
+		if out.In {
+			in = outValue(out)
+		}
+		if err := bss.callExec(ctx, session, "SET "+params[i]+" = ?", bss.bindArgs([]any{in})); err != nil {
+			return err
+		}
+	}
+	callSQL := "CALL " + proc + "(" + strings.Join(params, ", ") + ")"
+	if err := bss.callExec(ctx, session, callSQL, bss.bindArgs(callArgs)); err != nil {
+		return err
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	return bss.callScan(ctx, session, "SELECT "+strings.Join(vars, ", "), nil, dests)
+}
+
+func (bss *baseSqlSession) callPostgreSql(ctx context.Context, proc string, args []any) error {
+	params := make([]string, len(args))
+	callArgs := make([]any, len(args))
+	var dests []any
+	for i, arg := range args {
+		params[i] = "$" + strconv.Itoa(i+1)
+		out, ok := arg.(sql.Out)
+		if !ok {
+			callArgs[i] = arg
+			continue
+		}
+		// OUT 参数传入 NULL
+		dests = append(dests, out.Dest)
+		if out.In {
+			callArgs[i] = outValue(out)
+		}
+	}
+	callArgs = bss.bindArgs(callArgs)
+	for i, arg := range callArgs {
+		callArgs[i] = bindArray(arg)
+	}
+	callSQL := "CALL " + proc + "(" + strings.Join(params, ", ") + ")"
+	if len(dests) == 0 {
+		return bss.callExec(ctx, bss.dbSession, callSQL, callArgs)
+	}
+	// CALL 返回一条记录, 依次为 OUT 和 INOUT 参数的值
+	return bss.callScan(ctx, bss.dbSession, callSQL, callArgs, dests)
+}
+
+func (bss *baseSqlSession) callExec(ctx context.Context, session callSession, sqlText string, args []any) error {
+	if bss.logSql {
+		logSql(sqlText, args)
+	}
+	_, err := session.ExecContext(ctx, sqlText, args...)
+	return err
+}
+
+func (bss *baseSqlSession) callScan(ctx context.Context, session callSession, sqlText string, args []any, dests []any) error {
+	if bss.logSql {
+		logSql(sqlText, args)
+	}
+	return session.QueryRowContext(ctx, sqlText, args...).Scan(dests...)
+}
+
+// outValue 返回 INOUT 参数传入的值, Dest 已在 CallContext 中检查
+func outValue(out sql.Out) any {
+	return reflect.ValueOf(out.Dest).Elem().Interface()
+}
@@ -0,0 +1,91 @@
+package trysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestCallMySql(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"@trysql_out_1", "@trysql_out_2"}, []driver.Value{int64(3), "done"})
+	var total int64
+	status := "new"
+	err := NewMySqlSession(NewTxSession(db, false)).Call("count_users", 7, sql.Out{Dest: &total}, sql.Out{Dest: &status, In: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || status != "done" {
+		t.Fatalf("total = %v, status = %v", total, status)
+	}
+	execs := fake.executed()
+	if len(execs) != 4 || execs[1].args[0] != "new" || execs[2].query != "CALL count_users(?, @trysql_out_1, @trysql_out_2)" ||
+		execs[3].query != "SELECT @trysql_out_1, @trysql_out_2" {
+		t.Errorf("execs = %v", execs)
+	}
+}
+
+func TestCallPostgreSql(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"total"}, []driver.Value{int64(3)})
+	var total int64
+	if err := NewPostgreSqlSession(NewTxSession(db, false)).Call("count_users", 7, sql.Out{Dest: &total}); err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.executed()
+	if total != 3 || len(execs) != 1 || execs[0].query != "CALL count_users($1, $2)" || execs[0].args[1] != nil {
+		t.Errorf("total = %v, execs = %v", total, execs)
+	}
+
+	if err := NewPostgreSqlSession(NewTxSession(db, false)).Call("cleanup"); err != nil {
+		t.Fatal(err)
+	}
+	if execs = fake.executed(); execs[1].query != "CALL cleanup()" {
+		t.Errorf("execs = %v", execs)
+	}
+}
+
+func TestAsMultiList(t *testing.T) {
+	type user struct {
+		Id   int64
+		Name string
+	}
+	type order struct {
+		Id     int64
+		UserId int64
+	}
+	db, fake := newFakeDB()
+	fake.addResultSets(
+		fakeResult{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}},
+		fakeResult{columns: []string{"id", "user_id"}, rows: [][]driver.Value{{int64(10), int64(1)}}},
+	)
+	var users []user
+	var orders []*order
+	err := NewMySqlSession(NewTxSession(db, false)).AppendRaw("CALL user_orders(#{id})", 1).AsMultiList(&users, &orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Name != "b" || len(orders) != 1 || orders[0].UserId != 1 {
+		t.Errorf("users = %v, orders = %v", users, orders)
+	}
+
+	fake.addResult([]string{"id", "name"})
+	err = NewMySqlSession(NewTxSession(db, false)).AppendRaw("CALL user_orders(1)").AsMultiList(&users, &orders)
+	if err == nil {
+		t.Error("expected error for missing result set")
+	}
+}
+
+func TestCallInvalidOut(t *testing.T) {
+	db, fake := newFakeDB()
+	var total *int64
+	for _, dest := range []any{nil, int64(0), total} {
+		err := NewMySqlSession(NewTxSession(db, false)).Call("count_users", sql.Out{Dest: dest, In: true})
+		if err == nil {
+			t.Errorf("expected error for Dest %#v", dest)
+		}
+	}
+	if len(fake.executed()) != 0 {
+		t.Errorf("execs = %v", fake.executed())
+	}
+}
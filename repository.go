@@ -0,0 +1,177 @@
+package trysql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Condition Repository 的查询条件, 等同于 SqlSession.Where(Expr, Args...)
+type Condition struct {
+	Expr string
+	Args []any
+}
+
+// Cond 新建一个查询条件
+func Cond(expr string, args ...any) Condition {
+	return Condition{Expr: expr, Args: args}
+}
+
+// Repository 根据 T 的 colname tag 实现单表的增删改查, T 必须是 struct
+//
+//	type Order struct {
+//		ID      int64  `colname:"id,pk,auto,table=orders"`
+//		OrderNo string `colname:"order_no"`
+//		Created time.Time `colname:"create_time,readonly"`
+//	}
+//	repo := NewRepository[Order](ssf)
+type Repository[T any] struct {
+	ssf     SqlSessionFactory
+	session SqlSession
+	meta    *structMeta
+}
+
+// NewRepository 新建一个 Repository, T 不是 struct 时 panic
+func NewRepository[T any](ssf SqlSessionFactory) *Repository[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("expected struct, but %v", typ))
+	}
+	return &Repository[T]{ssf: ssf, meta: getStructMeta(typ)}
+}
+
+// WithSession 返回一个使用 sqlSession 数据库连接的 Repository, 用于在事务中执行
+func (r *Repository[T]) WithSession(sqlSession SqlSession) *Repository[T] {
+	return &Repository[T]{ssf: r.ssf, session: sqlSession, meta: r.meta}
+}
+
+// Table 返回 T 对应的表名
+func (r *Repository[T]) Table() string {
+	return r.meta.table
+}
+
+// Insert 插入一条记录, omitempty 的列为零值时不插入, 存在 auto 列时，将生成的主键回填到 entity
+func (r *Repository[T]) Insert(ctx context.Context, entity *T) error {
+	value := reflect.ValueOf(entity).Elem()
+	session := r.newSqlSession(ctx).InsertInto(r.meta.table)
+	for _, f := range r.meta.fields {
+		if v, ok := f.writeValue(value); ok {
+			session.Values(f.column, v)
+		}
+	}
+	auto := r.meta.auto
+	if auto == nil {
+		return session.DoneContext(ctx)
+	}
+	id, err := session.DoneInsertIdContext(ctx, auto.column)
+	if err != nil {
+		return err
+	}
+	fv := fieldValueAlloc(value, auto.index)
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fv.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fv.SetUint(uint64(id))
+	default:
+		return fmt.Errorf("auto column %s must be integer, but %v", auto.column, fv.Type())
+	}
+	return nil
+}
+
+// UpdateByPK 根据主键更新 pk, auto, readonly 以外的所有列, omitempty 的列为零值时不更新, 返回更新的记录数
+func (r *Repository[T]) UpdateByPK(ctx context.Context, entity *T) (int64, error) {
+	if err := r.checkPK(len(r.meta.pks)); err != nil {
+		return 0, err
+	}
+	value := reflect.ValueOf(entity).Elem()
+	session := r.newSqlSession(ctx).Update(r.meta.table)
+	updated := false
+	for _, f := range r.meta.fields {
+		if f.tag.pk {
+			continue
+		}
+		if v, ok := f.writeValue(value); ok {
+			session.Set(f.column, v)
+			updated = true
+		}
+	}
+	if !updated {
+		return 0, fmt.Errorf("update %s: no columns to update", r.meta.table)
+	}
+	pks := make([]any, len(r.meta.pks))
+	for i, f := range r.meta.pks {
+		fv, _ := fieldValue(value, f.index)
+		pks[i] = fv.Interface()
+	}
+	return r.wherePK(session, pks).DoneRowsAffectedContext(ctx)
+}
+
+// DeleteByPK 根据主键删除记录, pk 按 struct 中主键字段的顺序指定, 返回删除的记录数
+func (r *Repository[T]) DeleteByPK(ctx context.Context, pk ...any) (int64, error) {
+	if err := r.checkPK(len(pk)); err != nil {
+		return 0, err
+	}
+	session := r.newSqlSession(ctx).DeleteFrom(r.meta.table)
+	return r.wherePK(session, pk).DoneRowsAffectedContext(ctx)
+}
+
+// FindByPK 根据主键查询记录, pk 按 struct 中主键字段的顺序指定, 记录不存在时返回 nil
+func (r *Repository[T]) FindByPK(ctx context.Context, pk ...any) (*T, error) {
+	if err := r.checkPK(len(pk)); err != nil {
+		return nil, err
+	}
+	session := r.newSqlSession(ctx).Select(r.meta.columns()...).From(r.meta.table)
+	entity := new(T)
+	ok, err := r.wherePK(session, pk).AsSingleOKContext(ctx, entity)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// FindAll 查询满足所有条件的记录, 不指定条件时查询全表
+func (r *Repository[T]) FindAll(ctx context.Context, where ...Condition) ([]T, error) {
+	session := r.where(r.newSqlSession(ctx).Select(r.meta.columns()...).From(r.meta.table), where)
+	return List[T](ctx, session)
+}
+
+// Exists 是否存在满足所有条件的记录
+func (r *Repository[T]) Exists(ctx context.Context, where ...Condition) (bool, error) {
+	session := r.where(r.newSqlSession(ctx).Select("1").From(r.meta.table), where).Limit(1)
+	_, ok, err := First[int64](ctx, session)
+	return ok, err
+}
+
+// newSqlSession 新建 SqlSession, 未指定 WithSession 时加入 ctx 中的事务
+func (r *Repository[T]) newSqlSession(ctx context.Context) SqlSession {
+	if r.session != nil {
+		return r.session.New()
+	}
+	return r.ssf.NewSqlSessionContext(ctx)
+}
+
+func (r *Repository[T]) checkPK(n int) error {
+	if len(r.meta.pks) == 0 {
+		return fmt.Errorf("no pk field in %v", r.meta.typ)
+	}
+	if n != len(r.meta.pks) {
+		return fmt.Errorf("%v has %d pk fields, but got %d values", r.meta.typ, len(r.meta.pks), n)
+	}
+	return nil
+}
+
+// wherePK 添加主键条件, 参数名 #{:pk:列名} 不会与列名的参数名冲突
+func (r *Repository[T]) wherePK(session SqlSession, pk []any) SqlSession {
+	for i, f := range r.meta.pks {
+		session.Where(f.column+" = #{:pk:"+f.column+"}", pk[i])
+	}
+	return session
+}
+
+func (r *Repository[T]) where(session SqlSession, where []Condition) SqlSession {
+	for _, c := range where {
+		session.Where(c.Expr, c.Args...)
+	}
+	return session
+}
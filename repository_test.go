@@ -0,0 +1,128 @@
+package trysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type repoOrder struct {
+	ID         int64     `colname:"id,pk,auto,table=orders"`
+	OrderNo    string    `colname:"order_no"`
+	Amount     float64   `colname:"amount"`
+	CreateTime time.Time `colname:"create_time,readonly"`
+}
+
+func TestRepository_MySQL(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.lastInsertId = 99
+	repo := NewRepository[repoOrder](NewSqlSessionFactory(Mysql, db, time.Second, false))
+	ctx := context.Background()
+
+	order := repoOrder{OrderNo: "A001", Amount: 9.5}
+	if err := repo.Insert(ctx, &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.ID != 99 {
+		t.Errorf("id = %v", order.ID)
+	}
+
+	if _, err := repo.UpdateByPK(ctx, &order); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.DeleteByPK(ctx, 99); err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.executed()
+	want := []string{
+		"INSERT INTO orders\n (order_no, amount)\nVALUES (?, ?) ",
+		"UPDATE orders\nSET order_no = ?, amount = ?\nWHERE (id = ?) ",
+		"DELETE FROM orders\nWHERE (id = ?) ",
+	}
+	for i, w := range want {
+		if execs[i].query != w {
+			t.Errorf("query[%d] = %q, want %q", i, execs[i].query, w)
+		}
+	}
+	if fmt.Sprint(execs[1].args) != "[A001 9.5 99]" {
+		t.Errorf("update args = %v", execs[1].args)
+	}
+}
+
+func TestRepository_PG(t *testing.T) {
+	db, fake := newFakeDB()
+	repo := NewRepository[repoOrder](NewSqlSessionFactory(Postgresql, db, time.Second, false))
+	ctx := context.Background()
+
+	fake.addResult([]string{"id"}, []driver.Value{int64(5)})
+	order := repoOrder{OrderNo: "A002"}
+	if err := repo.Insert(ctx, &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.ID != 5 {
+		t.Errorf("id = %v", order.ID)
+	}
+
+	fake.addResult([]string{"id", "order_no", "amount", "create_time"},
+		[]driver.Value{int64(5), "A002", 1.5, time.Unix(0, 0)})
+	found, err := repo.FindByPK(ctx, 5)
+	if err != nil || found == nil || found.OrderNo != "A002" || found.Amount != 1.5 {
+		t.Fatalf("found = %v, err = %v", found, err)
+	}
+
+	fake.addResult([]string{"id", "order_no", "amount", "create_time"})
+	missing, err := repo.FindByPK(ctx, 6)
+	if err != nil || missing != nil {
+		t.Errorf("missing = %v, err = %v", missing, err)
+	}
+
+	fake.addResult([]string{"1"}, []driver.Value{int64(1)})
+	exists, err := repo.Exists(ctx, Cond("order_no = #{no}", "A002"))
+	if err != nil || !exists {
+		t.Errorf("exists = %v, err = %v", exists, err)
+	}
+
+	fake.addResult([]string{"id", "order_no"}, []driver.Value{int64(5), "A002"}, []driver.Value{int64(6), "A003"})
+	all, err := repo.FindAll(ctx, Cond("amount > #{amount}", 1))
+	if err != nil || len(all) != 2 {
+		t.Errorf("all = %v, err = %v", all, err)
+	}
+
+	execs := fake.executed()
+	if execs[0].query != "INSERT INTO orders\n (order_no, amount)\nVALUES ($1, $2) \n RETURNING id" {
+		t.Errorf("insert = %q", execs[0].query)
+	}
+	if execs[len(execs)-1].query != "SELECT id, order_no, amount, create_time\nFROM orders\nWHERE (amount > $1) " {
+		t.Errorf("find all = %q", execs[len(execs)-1].query)
+	}
+}
+
+type repoCounter struct {
+	ID   int64  `colname:"id,pk"`
+	PkID int64  `colname:"pk_id,omitempty"`
+	Name string `colname:"name,omitempty"`
+}
+
+func TestRepositoryUpdateByPK(t *testing.T) {
+	db, fake := newFakeDB()
+	repo := NewRepository[repoCounter](NewSqlSessionFactory(Postgresql, db, time.Second, false))
+	ctx := context.Background()
+
+	if _, err := repo.UpdateByPK(ctx, &repoCounter{ID: 1}); err == nil {
+		t.Error("expected error when no columns to update")
+	}
+	if len(fake.executed()) != 0 {
+		t.Fatalf("execs = %v", fake.executed())
+	}
+
+	// pk_id 列的参数不能与主键 id 的参数混淆
+	if _, err := repo.UpdateByPK(ctx, &repoCounter{ID: 1, PkID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	exec := fake.executed()[0]
+	if exec.query != "UPDATE repo_counter\nSET pk_id = $1\nWHERE (id = $2) " || fmt.Sprint(exec.args) != "[7 1]" {
+		t.Errorf("query = %q, args = %v", exec.query, exec.args)
+	}
+}
@@ -0,0 +1,101 @@
+package trysql
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+var (
+	// jitterRand 计算重试等待时间的随机数, go1.20 之前全局的 rand 没有自动设置种子
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMu   sync.Mutex
+)
+
+// jitter 返回 [0, n) 之间的随机数, rand.Rand 不是并发安全的, 需要加锁
+func jitter(n int64) int64 {
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return jitterRand.Int63n(n)
+}
+
+// RetryPolicy DoInTx 事务失败时的重试策略, 每次重试使用新的事务重新执行 SqlHandler
+type RetryPolicy struct {
+	// MaxAttempts 最多执行次数(包括第一次), 小于 1 时为 3
+	MaxAttempts int
+	// Backoff 第一次重试前的等待时间, 之后每次加倍, 实际等待时间在 [Backoff/2, Backoff] 之间随机, 默认 10ms
+	Backoff time.Duration
+	// MaxBackoff 最长等待时间, 默认 1s
+	MaxBackoff time.Duration
+	// Retryable 判断错误是否可以重试, 默认 IsRetryable
+	Retryable func(err error) bool
+}
+
+// WithRetry 指定 DoInTx 新建事务失败时的重试策略, 加入 ctx 中已经存在的事务时不重试。
+// 所有重试共用 DoInTx 的超时时间, 超时后返回最后一次的错误
+func WithRetry(policy RetryPolicy) TxOption {
+	return func(c *txConfig) {
+		c.retry = &policy
+	}
+}
+
+// IsRetryable 是否为可以重试的事务错误:
+// MySQL 1213(死锁), 1205(锁等待超时), PostgreSQL 40001(serialization_failure), 40P01(deadlock_detected)
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+	return false
+}
+
+// run 执行 fn, 失败且可以重试时等待后重新执行
+func (p *RetryPolicy) run(ctx context.Context, fn func() error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 3
+	}
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Second
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || !retryable(err) {
+			return err
+		}
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		wait := backoff/2 + time.Duration(jitter(int64(backoff/2)+1))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
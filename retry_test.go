@@ -0,0 +1,68 @@
+package trysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{&mysql.MySQLError{Number: 1213}, true},
+		{fmt.Errorf("update: %w", &mysql.MySQLError{Number: 1205}), true},
+		{&mysql.MySQLError{Number: 1062}, false},
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{errors.New("other"), false},
+	}
+	for _, c := range cases {
+		if IsRetryable(c.err) != c.retryable {
+			t.Errorf("IsRetryable(%v) = %v", c.err, !c.retryable)
+		}
+	}
+}
+
+func TestDoInTxRetry(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false)
+	attempts := 0
+	err := ssf.DoInTx(func(ctx context.Context, session SqlSession) error {
+		attempts++
+		if attempts < 3 {
+			return &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+		}
+		return nil
+	}, WithRetry(RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond}))
+	if err != nil || attempts != 3 || fake.begins != 3 || fake.rollbacks != 2 || fake.commits != 1 {
+		t.Errorf("err = %v, attempts = %v, begins = %v, rollbacks = %v", err, attempts, fake.begins, fake.rollbacks)
+	}
+
+	// 等待时间超过超时时间时不再重试
+	attempts = 0
+	err = ssf.DoInTxTimeoutContext(10*time.Millisecond, context.Background(), func(ctx context.Context, session SqlSession) error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	}, WithRetry(RetryPolicy{MaxAttempts: 5, Backoff: time.Second}))
+	if attempts != 1 || !IsRetryable(err) {
+		t.Errorf("err = %v, attempts = %v", err, attempts)
+	}
+
+	attempts = 0
+	errOther := errors.New("other")
+	err = ssf.DoInTx(func(ctx context.Context, session SqlSession) error {
+		attempts++
+		return errOther
+	}, WithRetry(RetryPolicy{}))
+	if err != errOther || attempts != 1 {
+		t.Errorf("err = %v, attempts = %v", err, attempts)
+	}
+}
@@ -6,13 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dennisge/trysql/sqltext"
+	"io"
 	"log"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/iancoleman/strcase"
 )
 
 // SqlSession 用于构建 SQL,非线程安全
@@ -138,18 +137,62 @@ type SqlSession interface {
 	// DoneRowsAffected 执行更新 SQL(Update, Delete)， 同时返回操作的记录数
 	DoneRowsAffected() (int64, error)
 
+	// DoneBatchInsertContext 执行 IntoMultiValues 构建的 INSERT SQL，超过参数数量或大小限制时拆分为多条依次执行，
+	// 在事务性 SqlSession 中所有 INSERT 在同一个事务中执行，AppendRaw 追加的子句（如 ON CONFLICT）追加到每条 INSERT，返回插入的总记录数
+	DoneBatchInsertContext(ctx context.Context, opts BatchInsertOptions) (int64, error)
+
+	// DoneBatchInsert 执行 IntoMultiValues 构建的 INSERT SQL，超过参数数量或大小限制时拆分为多条依次执行，返回插入的总记录数
+	DoneBatchInsert(opts BatchInsertOptions) (int64, error)
+
+	// AddBatch 将当前构建的 SQL 加入批量队列，之后可以继续构建下一条 SQL
+	AddBatch() SqlSession
+
+	// ExecuteBatchContext 按加入顺序执行 AddBatch 加入的 SQL，相同的 SQL 共用一条预处理语句，
+	// 返回每条 SQL 的操作记录数，遇到错误时停止执行
+	ExecuteBatchContext(ctx context.Context) ([]BatchResult, error)
+
+	// ExecuteBatch 按加入顺序执行 AddBatch 加入的 SQL，返回每条 SQL 的操作记录数，遇到错误时停止执行
+	ExecuteBatch() ([]BatchResult, error)
+
+	// CallContext 调用存储过程 proc，args 中的 sql.Out 参数在调用完成后写入 Dest
+	CallContext(ctx context.Context, proc string, args ...any) error
+
+	// Call 调用存储过程 proc，args 中的 sql.Out 参数在调用完成后写入 Dest
+	Call(proc string, args ...any) error
+
 	// AsSingleContext 执行 SQL，dest 是普通 struct 的引用指针
 	AsSingleContext(ctx context.Context, dest any) error
 
 	// AsSingle 执行 SQL，dest 是普通 struct 的引用指针
 	AsSingle(dest any) error
 
+	// AsSingleOKContext 执行 SQL，dest 是普通 struct 的引用指针，同时返回是否查询到记录
+	AsSingleOKContext(ctx context.Context, dest any) (bool, error)
+
+	// AsSingleOK 执行 SQL，dest 是普通 struct 的引用指针，同时返回是否查询到记录
+	AsSingleOK(dest any) (bool, error)
+
+	// AsOneContext 执行 SQL，dest 是普通 struct 的引用指针，没有记录时返回 ErrNoRows，多于一条记录时返回 ErrTooManyRows，返回错误时不修改 dest
+	AsOneContext(ctx context.Context, dest any) error
+
+	// AsOne 执行 SQL，dest 是普通 struct 的引用指针，没有记录时返回 ErrNoRows，多于一条记录时返回 ErrTooManyRows，返回错误时不修改 dest
+	AsOne(dest any) error
+
 	// AsListContext 执行 SQL，dest 是 slice 类型
+	//
+	// struct 同时存在主键(colname:"id,pk")和元素为 struct 的 slice 字段时，主键相同的记录合并为一个 struct,
+	// slice 字段从带有 "字段列名__" 前缀的列中填充，用于一对多的 JOIN 查询
 	AsListContext(ctx context.Context, dest any) error
 
 	// AsList 执行 SQL，dest 是 slice of struct 类型
 	AsList(dest any) error
 
+	// AsMultiListContext 执行返回多个结果集的 SQL（如 CALL 存储过程），按顺序将每个结果集映射到 dests 中对应的 slice of struct
+	AsMultiListContext(ctx context.Context, dests ...any) error
+
+	// AsMultiList 执行返回多个结果集的 SQL，按顺序将每个结果集映射到 dests 中对应的 slice of struct
+	AsMultiList(dests ...any) error
+
 	// AsPrimitiveContext 执行 SQL,dest 是 primitive 类型
 	AsPrimitiveContext(ctx context.Context, dest any) error
 
@@ -174,6 +217,36 @@ type SqlSession interface {
 	// AsMap 执行 SQL, 结果生成 Map 对象
 	AsMap() (map[string]any, error)
 
+	// AsMapOKContext 执行 SQL, 结果生成 Map 对象，同时返回是否查询到记录
+	AsMapOKContext(ctx context.Context) (map[string]any, bool, error)
+
+	// AsMapOK 执行 SQL, 结果生成 Map 对象，同时返回是否查询到记录
+	AsMapOK() (map[string]any, bool, error)
+
+	// AsTableContext 执行 SQL, 结果生成保留列顺序和数据库类型的 Table
+	AsTableContext(ctx context.Context) (*Table, error)
+
+	// AsTable 执行 SQL, 结果生成保留列顺序和数据库类型的 Table
+	AsTable() (*Table, error)
+
+	// ExportCSVContext 执行 SQL, 逐条将记录以 CSV 格式写入 w, 第一行为列名
+	ExportCSVContext(ctx context.Context, w io.Writer, opts CSVOptions) error
+
+	// ExportCSV 执行 SQL, 逐条将记录以 CSV 格式写入 w, 第一行为列名
+	ExportCSV(w io.Writer, opts CSVOptions) error
+
+	// ExportJSONLinesContext 执行 SQL, 逐条将记录以 JSON 对象写入 w, 每行一条记录, NULL 写入 null
+	ExportJSONLinesContext(ctx context.Context, w io.Writer) error
+
+	// ExportJSONLines 执行 SQL, 逐条将记录以 JSON 对象写入 w, 每行一条记录, NULL 写入 null
+	ExportJSONLines(w io.Writer) error
+
+	// Build 返回最终的 SQL 文本及有序参数，不执行 SQL，也不重置当前 SqlSession
+	Build() (string, []any, error)
+
+	// SQL 返回当前构建中的 sqltext.SQL，可用于读取各个子句
+	SQL() sqltext.SQL
+
 	// Reset 重置当前 SqlSession 以再次使用
 	Reset() SqlSession
 
@@ -183,6 +256,9 @@ type SqlSession interface {
 	// LogSql 是否输出 Sql 信息,必须在 SQL 构建执行之前(Done***, As***)调用
 	LogSql(logSql bool) SqlSession
 
+	// Mapping 指定查询结果映射到 struct 时列与字段不一致的处理方式, 对该 SqlSession 之后的查询都有效
+	Mapping(mode MappingMode) SqlSession
+
 	// DbSession SQL 最终代理到 该 DbSession 执行
 	DbSession
 }
@@ -195,16 +271,30 @@ func enabledLogSql(enabled bool) {
 	logSqlEnabled = enabled
 }
 
+// sessionOptions SqlSession 的配置, 由 SqlSessionFactory 传递给它创建的 SqlSession
+type sessionOptions struct {
+	converters  *ConverterRegistry
+	mappingMode MappingMode
+}
+
+func defaultSessionOptions() sessionOptions {
+	return sessionOptions{converters: defaultConverters}
+}
+
 type baseSqlSession struct {
 	sql       sqltext.SQL
 	argMap    map[string]any
 	rawSql    []string
 	dbSession DbSession
 	logSql    bool
+	dbType    DbType
+	options   sessionOptions
+	batch     []batchStatement
 }
 
-func newBaseSqlSession(db DbSession) *baseSqlSession {
-	return &baseSqlSession{dbSession: db, sql: sqltext.NewSQL(), argMap: map[string]any{}, logSql: logSqlEnabled}
+func newBaseSqlSession(db DbSession, dbType DbType, options sessionOptions) *baseSqlSession {
+	return &baseSqlSession{dbSession: db, sql: sqltext.NewSQL(), argMap: map[string]any{}, logSql: logSqlEnabled,
+		dbType: dbType, options: options}
 }
 
 func (bss *baseSqlSession) Select(columns ...string) {
@@ -461,14 +551,33 @@ func (bss *baseSqlSession) DoneRowsAffected(sqlText string, args []any) (int64,
 }
 
 func (bss *baseSqlSession) AsSingleContext(ctx context.Context, sqlText string, args []any, dest any) error {
+	_, err := bss.asSingleContext(ctx, sqlText, args, dest, false)
+	return err
+}
+
+func (bss *baseSqlSession) AsSingleOKContext(ctx context.Context, sqlText string, args []any, dest any) (bool, error) {
+	return bss.asSingleContext(ctx, sqlText, args, dest, false)
+}
+
+func (bss *baseSqlSession) AsOneContext(ctx context.Context, sqlText string, args []any, dest any) error {
+	found, err := bss.asSingleContext(ctx, sqlText, args, dest, true)
+	if err == nil && !found {
+		return ErrNoRows
+	}
+	return err
+}
+
+// asSingleContext 执行 SQL，将第一条记录映射到 dest，同时返回是否查询到记录,
+// one 为 true 时存在多条记录返回 ErrTooManyRows, 并且返回错误时不修改 dest
+func (bss *baseSqlSession) asSingleContext(ctx context.Context, sqlText string, args []any, dest any, one bool) (found bool, err error) {
 
 	if dest == nil {
-		return fmt.Errorf("scalar value cannot be nil")
+		return false, fmt.Errorf("scalar value cannot be nil")
 	}
 
 	rp := reflect.ValueOf(dest) // 指向存放查询结果的指针。
 	if rp.Kind() != reflect.Ptr {
-		return fmt.Errorf("dest must be pointer")
+		return false, fmt.Errorf("dest must be pointer")
 	}
 	if bss.logSql {
 		logSql(sqlText, args)
@@ -477,40 +586,113 @@ func (bss *baseSqlSession) AsSingleContext(ctx context.Context, sqlText string,
 	bss.Reset()
 	rows, err := bss.dbSession.QueryContext(ctx, sqlText, args...)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	defer func(rows *sql.Rows) {
-		err = rows.Close()
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
 	}(rows)
 
 	columns, _ := rows.Columns()
 
-	scanDest := getScanDest(rp.Elem(), columns)
+	plan, err := bss.scanPlan(rp.Elem().Type(), columns)
+	if err != nil {
+		return false, err
+	}
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+	if !one {
+		if err := plan.scan(rows, rp.Elem()); err != nil {
+			return false, err
+		}
+		return true, rows.Err()
+	}
 
+	// 确认只有一条记录后再写入 dest, 出错时 dest 保持不变
+	value := reflect.New(rp.Elem().Type()).Elem()
+	value.Set(rp.Elem())
+	if err := plan.scan(rows, value); err != nil {
+		return false, err
+	}
 	if rows.Next() {
-		if err := rows.Scan(scanDest...); err != nil {
+		return true, ErrTooManyRows
+	}
+	if err := rows.Err(); err != nil {
+		return true, err
+	}
+	rp.Elem().Set(value)
+	return true, nil
+}
+
+func (bss *baseSqlSession) AsListContext(ctx context.Context, sqlText string, args []any, dest any) error {
+
+	elemValue, sliceContentType, err := listDest(dest)
+	if err != nil {
+		return err
+	}
+
+	if bss.logSql {
+		logSql(sqlText, args)
+	}
+
+	bss.Reset()
+	rows, err := bss.dbSession.QueryContext(ctx, sqlText, args...)
+	if err != nil {
+		return err
+	}
+	defer func(rows *sql.Rows) {
+		err = rows.Close()
+	}(rows)
+
+	return bss.scanList(rows, elemValue, sliceContentType)
+}
+
+// AsMultiListContext 执行返回多个结果集的 SQL, 按顺序将每个结果集映射到 dests 中对应的 slice
+func (bss *baseSqlSession) AsMultiListContext(ctx context.Context, sqlText string, args []any, dests ...any) error {
+	elemValues := make([]reflect.Value, len(dests))
+	contentTypes := make([]reflect.Type, len(dests))
+	for i, dest := range dests {
+		elemValue, contentType, err := listDest(dest)
+		if err != nil {
 			return err
 		}
-	} else {
-		err := rows.Err()
-		if err != nil {
+		elemValues[i], contentTypes[i] = elemValue, contentType
+	}
+
+	rows, err := bss.queryContext(ctx, sqlText, args)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for i := range dests {
+		if i > 0 && !rows.NextResultSet() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("expected %d result sets, but %d", len(dests), i)
+		}
+		if err := bss.scanList(rows, elemValues[i], contentTypes[i]); err != nil {
 			return err
 		}
 	}
-	return err
+	return rows.Err()
 }
 
-func (bss *baseSqlSession) AsListContext(ctx context.Context, sqlText string, args []any, dest any) error {
-
+// listDest 检查 dest 是否为指向 struct 或 struct 指针的 slice 的指针, 返回 slice 及映射的 struct 类型
+func listDest(dest any) (reflect.Value, reflect.Type, error) {
 	value := reflect.ValueOf(dest) // 指向存放查询结果的切片的指针。
 	if value.Kind() != reflect.Ptr {
-		return fmt.Errorf("expected pointer to slice of struct, but %T", dest)
+		return reflect.Value{}, nil, fmt.Errorf("expected pointer to slice of struct, but %T", dest)
 	}
 	elemValue := value.Elem()       // 存放查询结果的切片。
 	elemType := value.Type().Elem() // 存放查询结果的切片的类型。
 	if elemType.Kind() != reflect.Slice {
-		return fmt.Errorf("eexpected pointer to slice of struct, but %T", elemValue)
+		return reflect.Value{}, nil, fmt.Errorf("eexpected pointer to slice of struct, but %T", elemValue)
 	}
 
 	resultType := elemType.Elem() // 存放查询结果的切片的元素的类型。
@@ -520,34 +702,34 @@ func (bss *baseSqlSession) AsListContext(ctx context.Context, sqlText string, ar
 	} else if resultType.Kind() == reflect.Ptr {
 		sliceContentType = resultType.Elem()
 		if sliceContentType.Kind() != reflect.Struct {
-			return fmt.Errorf("expected slice content is pointer or struct, but %T", sliceContentType)
+			return reflect.Value{}, nil, fmt.Errorf("expected slice content is pointer or struct, but %T", sliceContentType)
 		}
 	} else {
-		return fmt.Errorf("expected slice content is pointer or struct, but %T", resultType)
+		return reflect.Value{}, nil, fmt.Errorf("expected slice content is pointer or struct, but %T", resultType)
 	}
+	return elemValue, sliceContentType, nil
+}
 
-	if bss.logSql {
-		logSql(sqlText, args)
+// scanList 将 rows 当前结果集的记录映射为 sliceContentType 并追加到 elemValue
+func (bss *baseSqlSession) scanList(rows *sql.Rows, elemValue reflect.Value, sliceContentType reflect.Type) error {
+	columns, _ := rows.Columns()
+	if needAssembly(getStructMeta(sliceContentType)) {
+		a := getAssembler(sliceContentType, columns, bss.scanOptions())
+		if err := a.plan.checkMapping(bss.options.mappingMode, sliceContentType, columns); err != nil {
+			return err
+		}
+		return a.assembleList(rows, elemValue)
 	}
-
-	bss.Reset()
-	rows, err := bss.dbSession.QueryContext(ctx, sqlText, args...)
+	plan, err := bss.scanPlan(sliceContentType, columns)
 	if err != nil {
 		return err
 	}
-	defer func(rows *sql.Rows) {
-		err = rows.Close()
-	}(rows)
-
-	columns, _ := rows.Columns()
+	resultType := elemValue.Type().Elem()
 	for rows.Next() {
 		// 查询结果切片中的一个元素。
 		rowDest := reflect.New(sliceContentType).Elem()
 
-		scanDest := getScanDest(rowDest, columns)
-
-		err := rows.Scan(scanDest...)
-		if err != nil {
+		if err := plan.scan(rows, rowDest); err != nil {
 			return err
 		}
 
@@ -558,18 +740,39 @@ func (bss *baseSqlSession) AsListContext(ctx context.Context, sqlText string, ar
 			elemValue.Set(reflect.Append(elemValue, rowDest.Addr()))
 		}
 	}
-	return err
+	return nil
 }
 
-func getScanDest(rowDest reflect.Value, columns []string) []any {
-	scanDest := make([]any, len(columns))
-	getDest(rowDest, columns, scanDest)
-	for i, dest := range scanDest {
-		if dest == nil {
-			scanDest[i] = &sql.RawBytes{}
-		}
+// scanOptions 返回当前 SqlSession 映射查询结果的配置
+func (bss *baseSqlSession) scanOptions() scanOptions {
+	return scanOptions{converters: bss.options.converters.snapshot(), pgArray: bss.dbType == Postgresql}
+}
+
+// scanPlan 返回当前 SqlSession 配置下 typ 对应 columns 的 *scanPlan, 并按照 MappingMode 检查映射
+func (bss *baseSqlSession) scanPlan(typ reflect.Type, columns []string) (*scanPlan, error) {
+	plan := getScanPlan(typ, columns, bss.scanOptions())
+	if err := plan.checkMapping(bss.options.mappingMode, typ, columns); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// bindArgs 使用 Converter 转换 SQL 参数
+func (bss *baseSqlSession) bindArgs(args []any) []any {
+	cs := bss.options.converters.snapshot()
+	for i, arg := range args {
+		args[i] = cs.bind(arg)
 	}
-	return scanDest
+	return args
+}
+
+// queryContext 执行查询 SQL, 调用方负责关闭返回的 *sql.Rows
+func (bss *baseSqlSession) queryContext(ctx context.Context, sqlText string, args []any) (*sql.Rows, error) {
+	if bss.logSql {
+		logSql(sqlText, args)
+	}
+	bss.Reset()
+	return bss.dbSession.QueryContext(ctx, sqlText, args...)
 }
 
 func (bss *baseSqlSession) AsPrimitiveContext(ctx context.Context, sqlText string, args []any, dest any) error {
@@ -612,12 +815,12 @@ func (bss *baseSqlSession) AsPrimitiveListContext(ctx context.Context, sqlText s
 
 	resultType := elemType.Elem() // 存放查询结果的切片的元素的类型。
 	sliceContentType := resultType
-	if uint(resultType.Kind()) <= uint(reflect.Float64) {
+	if isPrimitiveType(resultType) {
 		// 期望的基本类型。
 	} else if resultType.Kind() == reflect.Ptr {
 		sliceContentType = resultType.Elem()
-		if uint(sliceContentType.Kind()) > uint(reflect.Float64) {
-			return fmt.Errorf("expected slice content is pointer or struct, but %T", sliceContentType)
+		if !isPrimitiveType(sliceContentType) {
+			return fmt.Errorf("expected slice content is pointer or primitive, but %v", sliceContentType)
 		}
 	} else {
 		return fmt.Errorf("expected slice content is pointer or primitive, but %T", resultType)
@@ -655,29 +858,42 @@ func (bss *baseSqlSession) AsMapListContext(ctx context.Context, sqlText string,
 	}(rows)
 
 	columns, _ := rows.Columns()
+	types, err := columnTypeNames(rows)
+	if err != nil {
+		return nil, err
+	}
 	r := make([]map[string]any, 0)
 	for rows.Next() {
-		results := make([]any, len(columns))
-		resultPointers := make([]any, len(columns))
-		for i := range columns {
-			resultPointers[i] = &results[i]
-		}
-
-		if err := rows.Scan(resultPointers...); err != nil {
+		m, err := scanMap(rows, columns, types)
+		if err != nil {
 			return nil, err
 		}
-		m := make(map[string]any)
-		for i, colName := range columns {
-			val := resultPointers[i].(*any)
-			m[colName] = *val
-		}
 		r = append(r, m)
 	}
 
-	return r, nil
+	return r, rows.Err()
+}
+
+// scanMap 将当前记录映射为 Map 对象, 列值按照数据库类型 types 转换
+func scanMap(rows *sql.Rows, columns []string, types []string) (map[string]any, error) {
+	results, err := scanValues(rows, types)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any, len(columns))
+	for i, colName := range columns {
+		m[colName] = results[i]
+	}
+	return m, nil
 }
 
 func (bss *baseSqlSession) AsMapContext(ctx context.Context, sqlText string, args []any) (map[string]any, error) {
+	m, _, err := bss.AsMapOKContext(ctx, sqlText, args)
+	return m, err
+}
+
+// AsMapOKContext 执行 SQL，第一条记录生成 Map 对象，同时返回是否查询到记录，没有记录时 Map 中的值都为 nil
+func (bss *baseSqlSession) AsMapOKContext(ctx context.Context, sqlText string, args []any) (m map[string]any, found bool, err error) {
 
 	if bss.logSql {
 		logSql(sqlText, args)
@@ -686,37 +902,84 @@ func (bss *baseSqlSession) AsMapContext(ctx context.Context, sqlText string, arg
 	bss.Reset()
 	rows, err := bss.dbSession.QueryContext(ctx, sqlText, args...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	defer func(rows *sql.Rows) {
-		err = rows.Close()
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
 	}(rows)
 
 	columns, _ := rows.Columns()
-
-	results := make([]any, len(columns))
-	resultPointers := make([]any, len(columns))
-	for i := range columns {
-		resultPointers[i] = &results[i]
+	types, err := columnTypeNames(rows)
+	if err != nil {
+		return nil, false, err
 	}
 
 	if rows.Next() {
-		if err := rows.Scan(resultPointers...); err != nil {
-			return nil, err
+		m, err := scanMap(rows, columns, types)
+		return m, err == nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	m = make(map[string]any, len(columns))
+	for _, colName := range columns {
+		m[colName] = nil
+	}
+	return m, false, nil
+}
+
+func (bss *baseSqlSession) AsTableContext(ctx context.Context, sqlText string, args []any) (table *Table, err error) {
+	rows, err := bss.queryContext(ctx, sqlText, args)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
 		}
-	} else {
-		err := rows.Err()
+	}(rows)
+
+	table = &Table{Rows: make([][]any, 0)}
+	if table.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	if table.Types, err = columnTypeNames(rows); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		values, err := scanValues(rows, table.Types)
 		if err != nil {
 			return nil, err
 		}
+		table.Rows = append(table.Rows, values)
 	}
-	kvMap := make(map[string]any)
-	for i, colName := range columns {
-		val := resultPointers[i].(*any)
-		kvMap[colName] = *val
+	return table, rows.Err()
+}
+
+var (
+	// ErrNoRows AsOne 等查询没有记录
+	ErrNoRows = sql.ErrNoRows
+	// ErrTooManyRows AsOne 查询到多条记录
+	ErrTooManyRows = errors.New("trysql: expected one row, but more than one")
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// isPrimitiveType 是否可以直接作为 rows.Scan 目标的类型，包括基本类型、[]byte、time.Time 和 sql.Scanner
+func isPrimitiveType(t reflect.Type) bool {
+	if uint(t.Kind()) <= uint(reflect.Float64) || t.Kind() == reflect.String {
+		return true
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return true
 	}
-	return kvMap, nil
+	return t == timeType || reflect.PointerTo(t).Implements(scannerType)
 }
 
 func isNotZero(value any) bool {
@@ -817,43 +1080,6 @@ func logSql(sqlText string, args []any) {
 	log.Printf("----- Parameter -----\n%v", b.String())
 }
 
-func getDest(value reflect.Value, columns []string, dest []any) {
-	typ := value.Type()
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		v := value.Field(i)
-		if field.IsExported() {
-			if field.Anonymous {
-				if v.Type().Kind() == reflect.Pointer {
-					f := reflect.New(v.Type().Elem()).Elem()
-					v.Set(f.Addr())
-					getDest(f, columns, dest)
-				} else {
-					f := reflect.New(v.Type()).Elem()
-					v.Set(f)
-					getDest(v, columns, dest)
-				}
-			} else {
-				var fieldName string
-				if columnName, ok := field.Tag.Lookup("colname"); ok {
-					fieldName = columnName
-				} else {
-					fieldName = strcase.ToSnake(field.Name)
-				}
-				for index, name := range columns {
-					if name == fieldName {
-						if v.Kind() == reflect.Pointer {
-							dest[index] = v.Interface()
-						} else {
-							dest[index] = v.Addr().Interface()
-						}
-					}
-				}
-			}
-		}
-	}
-}
-
 func getPlaceholder(s string) []string {
 	sIndex := -1
 	placeholders := make([]string, 0)
@@ -904,6 +1130,30 @@ func (bss *baseSqlSession) fillArgValue(sqlText string, value any) {
 	}
 }
 
+// checkParams 检查 SQL 中的每个参数占位符都已指定参数值
+func (bss *baseSqlSession) checkParams() error {
+	for _, ph := range getPlaceholder(bss.getSqlText()) {
+		if _, ok := bss.argMap[ph]; !ok {
+			return fmt.Errorf("missing value for SQL parameter %s", ph)
+		}
+	}
+	return nil
+}
+
+// checkArgs 检查转换失败的 SQL 参数
+func checkArgs(args []any) error {
+	for _, arg := range args {
+		if b, ok := arg.(bindError); ok {
+			return b.err
+		}
+	}
+	return nil
+}
+
+func (bss *baseSqlSession) SQL() sqltext.SQL {
+	return bss.sql
+}
+
 func (bss *baseSqlSession) getSqlText() string {
 	var sqlText = bss.sql.String()
 	if len(sqlText) == 0 {
@@ -14,7 +14,7 @@ const (
 	Postgresql
 )
 
-// SqlHandler sql 执行函数 ， ctx 是 Timeout Context
+// SqlHandler sql 执行函数 ， ctx 是 Timeout Context, 在事务中执行时 ctx 保存了当前事务
 type SqlHandler func(ctx context.Context, sqlSession SqlSession) error
 
 type SqlSessionFactory interface {
@@ -22,6 +22,9 @@ type SqlSessionFactory interface {
 	// NewSqlSession 新建一个 非事务 SqlSession
 	NewSqlSession() SqlSession
 
+	// NewSqlSessionContext 新建一个 SqlSession, ctx 中存在 DoInTx 开启的事务时加入该事务, 否则为非事务
+	NewSqlSessionContext(ctx context.Context) SqlSession
+
 	// NewTxSqlSession 新建一个 事务性 SqlSession, dbSession 提供事务支持
 	NewTxSqlSession(dbSession DbSession) SqlSession
 
@@ -43,7 +46,7 @@ type SqlSessionFactory interface {
 	// NewTimeoutContext 新建一个 Timeout Context
 	NewTimeoutContext(ctx context.Context, duration ...time.Duration) (context.Context, context.CancelFunc)
 
-	// DoTimeoutContext 执行 非事务 Sql 查询，指定超时时间
+	// DoTimeoutContext 执行 非事务 Sql 查询，指定超时时间, ctx 中存在事务时加入该事务
 	DoTimeoutContext(timeout time.Duration, ctx context.Context, sqlHandler SqlHandler) error
 
 	// DoContext 执行 非事务 Sql 查询，使用默认超时
@@ -53,29 +56,86 @@ type SqlSessionFactory interface {
 	Do(sqlHandler SqlHandler) error
 
 	// DoInTxTimeoutContext 在一个事务中 执行 Sql 查询，指定超时时间
-	DoInTxTimeoutContext(timeout time.Duration, ctx context.Context, sqlHandler SqlHandler) error
+	//
+	// 事务保存在传给 sqlHandler 的 ctx 中，ctx 中已经存在事务时按照 WithPropagation 指定的传播行为处理，默认加入该事务
+	DoInTxTimeoutContext(timeout time.Duration, ctx context.Context, sqlHandler SqlHandler, opts ...TxOption) error
 
 	// DoInTxContext 在一个事务中 执行 Sql 查询，使用默认超时
-	DoInTxContext(ctx context.Context, sqlHandler SqlHandler) error
+	DoInTxContext(ctx context.Context, sqlHandler SqlHandler, opts ...TxOption) error
 
 	// DoInTx 在一个事务中 执行 Sql 查询，使用默认超时
-	DoInTx(sqlHandler SqlHandler) error
+	DoInTx(sqlHandler SqlHandler, opts ...TxOption) error
+
+	// DoInTxWithOptions 在一个指定隔离级别和是否只读的事务中 执行 Sql 查询，使用默认超时
+	DoInTxWithOptions(ctx context.Context, opts *sql.TxOptions, sqlHandler SqlHandler, txOpts ...TxOption) error
+
+	// DoReadOnly 在一个只读事务中 执行 Sql 查询，使用默认超时
+	//
+	// go-sql-driver/mysql 和 lib/pq 通过 BeginTx 开启只读事务，驱动不支持 sql.TxOptions 时使用 SET TRANSACTION READ ONLY
+	DoReadOnly(ctx context.Context, sqlHandler SqlHandler, txOpts ...TxOption) error
+
+	// DoSerializable 在一个 SERIALIZABLE 隔离级别的事务中 执行 Sql 查询，使用默认超时
+	//
+	// go-sql-driver/mysql 和 lib/pq 通过 BeginTx 设置隔离级别，驱动不支持 sql.TxOptions 时使用 SET TRANSACTION ISOLATION LEVEL SERIALIZABLE
+	DoSerializable(ctx context.Context, sqlHandler SqlHandler, txOpts ...TxOption) error
+
+	// StmtCacheStats 返回预处理语句缓存的统计信息，未启用缓存时返回零值
+	StmtCacheStats() StmtCacheStats
+
+	// Close 关闭预处理语句缓存，通过 NewSqlSessionFactoryByDSN 创建时同时关闭数据库连接
+	Close() error
 }
 type DefaultSqlSessionFactory struct {
 	dbType         DbType
 	db             *sql.DB
 	nonTxDbSession DbSession
 	sqlTimeout     time.Duration
+	options        sessionOptions
+	stmtCacheSize  int
+	stmtCache      *stmtCache
+	// ownDB db 是否由 SqlSessionFactory 创建
+	ownDB bool
+}
+
+// FactoryOption DefaultSqlSessionFactory 的可选配置
+type FactoryOption func(ssf *DefaultSqlSessionFactory)
+
+// WithConverters 指定 SqlSessionFactory 创建的 SqlSession 使用的 ConverterRegistry
+func WithConverters(registry *ConverterRegistry) FactoryOption {
+	return func(ssf *DefaultSqlSessionFactory) {
+		ssf.options.converters = registry
+	}
+}
+
+// WithMappingMode 指定 SqlSessionFactory 创建的 SqlSession 查询结果映射到 struct 时列与字段不一致的处理方式
+func WithMappingMode(mode MappingMode) FactoryOption {
+	return func(ssf *DefaultSqlSessionFactory) {
+		ssf.options.mappingMode = mode
+	}
+}
+
+// WithStmtCache 启用预处理语句缓存，按最终 SQL 文本缓存最近使用的 size 个 *sql.Stmt,
+// 事务中通过 tx.StmtContext 绑定到事务后使用
+func WithStmtCache(size int) FactoryOption {
+	return func(ssf *DefaultSqlSessionFactory) {
+		ssf.stmtCacheSize = size
+	}
 }
 
 // NewSqlSessionFactory 新建一个 SqlSessionFactory，sqlTimeout 指定一个 SqlSession的 执行超时时间
-func NewSqlSessionFactory(dbType DbType, db *sql.DB, sqlTimeout time.Duration, logSqlEnabled bool) SqlSessionFactory {
+func NewSqlSessionFactory(dbType DbType, db *sql.DB, sqlTimeout time.Duration, logSqlEnabled bool, opts ...FactoryOption) SqlSessionFactory {
 	var ssf = &DefaultSqlSessionFactory{}
 	ssf.db = db
-	session := NewTxSession(db, false)
-	ssf.nonTxDbSession = session
 	ssf.dbType = dbType
 	ssf.sqlTimeout = sqlTimeout
+	ssf.options = defaultSessionOptions()
+	for _, opt := range opts {
+		opt(ssf)
+	}
+	if ssf.stmtCacheSize > 0 {
+		ssf.stmtCache = newStmtCache(db, ssf.stmtCacheSize)
+	}
+	ssf.nonTxDbSession = &NonTxDbSession{DB: db, stmts: ssf.stmtCache}
 	enabledLogSql(logSqlEnabled)
 	return ssf
 }
@@ -83,7 +143,7 @@ func NewSqlSessionFactory(dbType DbType, db *sql.DB, sqlTimeout time.Duration, l
 // NewSqlSessionFactoryByDSN 初始化数据配置。
 // dsn 数据库连接字符串。
 // 尝试根据指定的连接字符串创建数据库连接并且Ping，如果成功则返回nil，否则返回连接时发生的错误。
-func NewSqlSessionFactoryByDSN(dbType DbType, dsn string, maxActive, maxIdle int, connMaxLifetime, connMaxIdleTime, sqlTimeout time.Duration, logSqlEnabled bool) (SqlSessionFactory, error) {
+func NewSqlSessionFactoryByDSN(dbType DbType, dsn string, maxActive, maxIdle int, connMaxLifetime, connMaxIdleTime, sqlTimeout time.Duration, logSqlEnabled bool, opts ...FactoryOption) (SqlSessionFactory, error) {
 	var driverName string
 	if dbType == Mysql {
 		driverName = "mysql"
@@ -111,15 +171,29 @@ func NewSqlSessionFactoryByDSN(dbType DbType, dsn string, maxActive, maxIdle int
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
-	return NewSqlSessionFactory(dbType, db, sqlTimeout, logSqlEnabled), nil
+	ssf := NewSqlSessionFactory(dbType, db, sqlTimeout, logSqlEnabled, opts...)
+	ssf.(*DefaultSqlSessionFactory).ownDB = true
+	return ssf, nil
 }
 
 func (ssf *DefaultSqlSessionFactory) NewSqlSession() SqlSession {
+	return ssf.newSqlSession(ssf.nonTxDbSession)
+}
+
+func (ssf *DefaultSqlSessionFactory) NewSqlSessionContext(ctx context.Context) SqlSession {
+	if dbSession := ssf.currentTx(ctx); dbSession != nil {
+		return ssf.newSqlSession(dbSession)
+	}
+	return ssf.newSqlSession(ssf.nonTxDbSession)
+}
+
+// newSqlSession 新建一个使用 dbSession 和 SqlSessionFactory 配置的 SqlSession
+func (ssf *DefaultSqlSessionFactory) newSqlSession(dbSession DbSession) SqlSession {
 	switch ssf.dbType {
 	case Postgresql:
-		return NewPostgreSqlSession(ssf.nonTxDbSession)
+		return newPostgreSqlSession(dbSession, ssf.options)
 	case Mysql:
-		return NewMySqlSession(ssf.nonTxDbSession)
+		return newMySqlSession(dbSession, ssf.options)
 	default:
 		// 不会执行到此
 		return nil
@@ -138,25 +212,33 @@ func (ssf *DefaultSqlSessionFactory) NewTimeoutContext(ctx context.Context, dura
 }
 
 func (ssf *DefaultSqlSessionFactory) NewTxDbSessionContext(ctx context.Context, opts *sql.TxOptions) DbSession {
-	return NewTxSessionContext(ssf.db, true, ctx, opts)
+	dbSession, err := ssf.newTxDbSession(ctx, opts)
+	if err != nil {
+		panic(err)
+	}
+	return dbSession
 }
 
-func (ssf *DefaultSqlSessionFactory) NewTxSqlSession(dbSession DbSession) SqlSession {
-	switch ssf.dbType {
-	case Postgresql:
-		return NewPostgreSqlSession(dbSession)
-	case Mysql:
-		return NewMySqlSession(dbSession)
-	default:
-		// 不会执行到此
-		return nil
+// newTxDbSession 开启事务并返回使用 SqlSessionFactory 配置的 *TxDbSession
+func (ssf *DefaultSqlSessionFactory) newTxDbSession(ctx context.Context, opts *sql.TxOptions) (*TxDbSession, error) {
+	dbSession, err := ssf.beginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if ssf.stmtCache != nil {
+		dbSession.stmts = &txStmts{cache: ssf.stmtCache}
 	}
+	return dbSession, nil
+}
+
+func (ssf *DefaultSqlSessionFactory) NewTxSqlSession(dbSession DbSession) SqlSession {
+	return ssf.newSqlSession(dbSession)
 }
 
 func (ssf *DefaultSqlSessionFactory) DoTimeoutContext(timeout time.Duration, ctx context.Context, sqlHandler SqlHandler) error {
 	timeoutContext, cancelFunc := ssf.NewTimeoutContext(ctx, timeout)
 	defer cancelFunc()
-	sqlSession := ssf.NewSqlSession()
+	sqlSession := ssf.NewSqlSessionContext(timeoutContext)
 	return sqlHandler(timeoutContext, sqlSession)
 }
 
@@ -168,20 +250,96 @@ func (ssf *DefaultSqlSessionFactory) Do(sqlHandler SqlHandler) error {
 	return ssf.DoTimeoutContext(ssf.sqlTimeout, context.TODO(), sqlHandler)
 }
 
-func (ssf *DefaultSqlSessionFactory) DoInTxTimeoutContext(timeout time.Duration, ctx context.Context, sqlHandler SqlHandler) error {
+func (ssf *DefaultSqlSessionFactory) DoInTxTimeoutContext(timeout time.Duration, ctx context.Context, sqlHandler SqlHandler, opts ...TxOption) error {
+	config := newTxConfig(opts)
+	current := ssf.currentTx(ctx)
+	switch config.propagation {
+	case PropagationRequired:
+		if current != nil {
+			return ssf.joinTx(timeout, ctx, current, sqlHandler)
+		}
+	case PropagationNested:
+		if current != nil {
+			// SAVEPOINT 中的错误只回滚到该 SAVEPOINT, 不标记 rollback-only
+			return current.InTxContext(ctx, func() error {
+				return ssf.runInTx(timeout, ctx, current, sqlHandler)
+			})
+		}
+	case PropagationSupports:
+		if current != nil {
+			return ssf.joinTx(timeout, ctx, current, sqlHandler)
+		}
+		return ssf.DoTimeoutContext(timeout, ctx, sqlHandler)
+	case PropagationNotSupported:
+		return ssf.DoTimeoutContext(timeout, ssf.withTx(ctx, nil), sqlHandler)
+	case PropagationMandatory:
+		if current == nil {
+			return ErrNoTransaction
+		}
+		return ssf.joinTx(timeout, ctx, current, sqlHandler)
+	}
+
 	timeoutContext, cancelFunc := ssf.NewTimeoutContext(ctx, timeout)
 	defer cancelFunc()
-	dbSession := ssf.NewTxDbSessionContext(timeoutContext, nil)
+	if config.retry == nil {
+		return ssf.doInNewTx(timeoutContext, config.txOptions, sqlHandler)
+	}
+	return config.retry.run(timeoutContext, func() error {
+		return ssf.doInNewTx(timeoutContext, config.txOptions, sqlHandler)
+	})
+}
+
+// doInNewTx 新建事务执行 sqlHandler, 事务保存在传给 sqlHandler 的 ctx 中
+func (ssf *DefaultSqlSessionFactory) doInNewTx(ctx context.Context, opts *sql.TxOptions, sqlHandler SqlHandler) error {
+	dbSession, err := ssf.newTxDbSession(ctx, opts)
+	if err != nil {
+		return err
+	}
 	sqlSession := ssf.NewTxSqlSession(dbSession)
-	return dbSession.InTx(func() error {
-		return sqlHandler(timeoutContext, sqlSession)
+	txContext := ssf.withTx(ctx, dbSession)
+	return dbSession.InTxContext(ctx, func() error {
+		return sqlHandler(txContext, sqlSession)
 	})
 }
 
-func (ssf *DefaultSqlSessionFactory) DoInTxContext(ctx context.Context, sqlHandler SqlHandler) error {
-	return ssf.DoInTxTimeoutContext(ssf.sqlTimeout, ctx, sqlHandler)
+// joinTx 在 ctx 中已经存在的事务 dbSession 中执行 sqlHandler, 由开启事务的 DoInTx 提交或回滚,
+// sqlHandler 返回错误时将事务标记为 rollback-only
+func (ssf *DefaultSqlSessionFactory) joinTx(timeout time.Duration, ctx context.Context, dbSession *TxDbSession, sqlHandler SqlHandler) error {
+	err := ssf.runInTx(timeout, ctx, dbSession, sqlHandler)
+	if err != nil {
+		dbSession.rollbackOnly = true
+	}
+	return err
+}
+
+// runInTx 在事务 dbSession 中执行 sqlHandler
+func (ssf *DefaultSqlSessionFactory) runInTx(timeout time.Duration, ctx context.Context, dbSession *TxDbSession, sqlHandler SqlHandler) error {
+	timeoutContext, cancelFunc := ssf.NewTimeoutContext(ctx, timeout)
+	defer cancelFunc()
+	return sqlHandler(timeoutContext, ssf.NewTxSqlSession(dbSession))
+}
+
+func (ssf *DefaultSqlSessionFactory) DoInTxContext(ctx context.Context, sqlHandler SqlHandler, opts ...TxOption) error {
+	return ssf.DoInTxTimeoutContext(ssf.sqlTimeout, ctx, sqlHandler, opts...)
+}
+
+func (ssf *DefaultSqlSessionFactory) DoInTx(sqlHandler SqlHandler, opts ...TxOption) error {
+	return ssf.DoInTxTimeoutContext(ssf.sqlTimeout, context.TODO(), sqlHandler, opts...)
+}
+
+func (ssf *DefaultSqlSessionFactory) StmtCacheStats() StmtCacheStats {
+	if ssf.stmtCache == nil {
+		return StmtCacheStats{}
+	}
+	return ssf.stmtCache.statistics()
 }
 
-func (ssf *DefaultSqlSessionFactory) DoInTx(sqlHandler SqlHandler) error {
-	return ssf.DoInTxTimeoutContext(ssf.sqlTimeout, context.TODO(), sqlHandler)
+func (ssf *DefaultSqlSessionFactory) Close() error {
+	if ssf.stmtCache != nil {
+		_ = ssf.stmtCache.close()
+	}
+	if ssf.ownDB {
+		return ssf.db.Close()
+	}
+	return nil
 }
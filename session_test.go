@@ -0,0 +1,147 @@
+package trysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsSingleOKAndAsOne(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewMySqlSession(NewTxSession(db, false))
+
+	fake.addResult([]string{"id", "name"})
+	var user genericUser
+	if ok, err := session.Select("*").From("user").AsSingleOK(&user); ok || err != nil {
+		t.Errorf("ok = %v, err = %v", ok, err)
+	}
+
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(1), ""})
+	if ok, err := session.Select("*").From("user").AsSingleOK(&user); !ok || err != nil || user.ID != 1 {
+		t.Errorf("ok = %v, err = %v, user = %+v", ok, err, user)
+	}
+
+	fake.addResult([]string{"id", "name"})
+	if err := session.Select("*").From("user").AsOne(&user); !errors.Is(err, ErrNoRows) {
+		t.Errorf("err = %v", err)
+	}
+
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(2), "a"}, []driver.Value{int64(3), "b"})
+	before := user
+	if err := session.Select("*").From("user").AsOne(&user); err != ErrTooManyRows || user != before {
+		t.Errorf("err = %v, user = %+v", err, user)
+	}
+
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(3), "c"})
+	if err := session.Select("*").From("user").AsOne(&user); err != nil || user.ID != 3 {
+		t.Errorf("err = %v, user = %+v", err, user)
+	}
+}
+
+func TestAsMapOK(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewPostgreSqlSession(NewTxSession(db, false))
+
+	fake.addResult([]string{"id"})
+	m, ok, err := session.Select("id").From("user").AsMapOK()
+	if ok || err != nil || len(m) != 1 || m["id"] != nil {
+		t.Errorf("m = %v, ok = %v, err = %v", m, ok, err)
+	}
+
+	fake.addResult([]string{"id"}, []driver.Value{int64(0)})
+	m, ok, err = session.Select("id").From("user").AsMapOK()
+	if !ok || err != nil || m["id"] != int64(0) {
+		t.Errorf("m = %v, ok = %v, err = %v", m, ok, err)
+	}
+}
+
+func TestColumnTypeAwareMap(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewMySqlSession(NewTxSession(db, false))
+	columns := []string{"name", "price", "qty", "big", "rate", "created", "data", "note"}
+	types := []string{"VARCHAR", "DECIMAL", "INT", "UNSIGNED BIGINT", "DOUBLE", "DATETIME", "BLOB", "TEXT"}
+	row := []driver.Value{[]byte("a"), []byte("12.30"), []byte("7"), []byte("18446744073709551615"),
+		[]byte("1.5"), []byte("2024-01-02 03:04:05"), []byte{1, 2}, nil}
+
+	fake.addTypedResult(columns, types, row)
+	list, err := session.Select("*").From("t").AsMapList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := list[0]
+	created, _ := m["created"].(time.Time)
+	if m["name"] != "a" || m["price"] != "12.30" || m["qty"] != int64(7) || m["big"] != uint64(18446744073709551615) ||
+		m["rate"] != 1.5 || created.Year() != 2024 || m["note"] != nil {
+		t.Errorf("m = %#v", m)
+	}
+	if data, ok := m["data"].([]byte); !ok || len(data) != 2 {
+		t.Errorf("data = %#v", m["data"])
+	}
+
+	fake.addTypedResult(columns, types, row, row)
+	table, err := session.Select("*").From("t").AsTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(table.Columns) != fmt.Sprint(columns) || table.Types[1] != "DECIMAL" || len(table.Rows) != 2 {
+		t.Errorf("table = %+v", table)
+	}
+	if table.Rows[1][0] != "a" || table.Map(0)["qty"] != int64(7) {
+		t.Errorf("rows = %v", table.Rows)
+	}
+}
+
+func TestExport(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewPostgreSqlSession(NewTxSession(db, false))
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"id", "name", "amount", "created"}
+	rows := [][]driver.Value{{int64(1), "a,b", 1.5, created}, {int64(2), nil, nil, nil}}
+
+	fake.addResult(columns, rows...)
+	b := strings.Builder{}
+	if err := session.Select("*").From("t").ExportCSV(&b, CSVOptions{Null: "NULL"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,amount,created\n1,\"a,b\",1.5,2024-01-02T03:04:05Z\n2,NULL,NULL,NULL\n"
+	if b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+
+	fake.addResult(columns, rows...)
+	b.Reset()
+	if err := session.Select("*").From("t").ExportCSV(&b, CSVOptions{Comma: '\t', BOM: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "\uFEFFid\tname") || !strings.HasSuffix(b.String(), "2\t\t\t\n") {
+		t.Errorf("tsv = %q", b.String())
+	}
+
+	fake.addResult(columns, rows...)
+	b.Reset()
+	if err := session.Select("*").From("t").ExportJSONLines(&b); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"id":1,"name":"a,b","amount":1.5,"created":"2024-01-02T03:04:05Z"}` + "\n" +
+		`{"id":2,"name":null,"amount":null,"created":null}` + "\n"
+	if b.String() != want {
+		t.Errorf("json = %q, want %q", b.String(), want)
+	}
+
+	// 查询失败时不写入 BOM
+	b.Reset()
+	err := session.Select("*").From("t").Where("id = #{id}", failingValuer{}).ExportCSV(&b, CSVOptions{BOM: true})
+	if err == nil || b.Len() != 0 {
+		t.Errorf("err = %v, csv = %q", err, b.String())
+	}
+}
+
+// failingValuer 转换为数据库值时返回错误, 用于模拟查询失败
+type failingValuer struct{}
+
+func (failingValuer) Value() (driver.Value, error) {
+	return nil, errors.New("invalid value")
+}
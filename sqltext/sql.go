@@ -35,6 +35,8 @@ type SQL interface {
 	FetchFirstRowsOnly(limit string)
 	OffsetRows(offset string)
 	String() string
+	// Statement 返回当前构建的语句，用于读取各个子句
+	Statement() *Statement
 }
 
 // builder 用于构建 sqltext text
@@ -173,6 +175,10 @@ func (b *builder) String() string {
 	return builder.String()
 }
 
+func (b *builder) Statement() *Statement {
+	return b.stmt
+}
+
 type statementType int
 
 const (
@@ -205,6 +211,124 @@ type Statement struct {
 	limitingRowsStrategy limitingRowsStrategy
 }
 
+// IsSelect 是否 SELECT 语句
+func (s *Statement) IsSelect() bool {
+	return s.statementType == doSelect
+}
+
+// IsInsert 是否 INSERT 语句
+func (s *Statement) IsInsert() bool {
+	return s.statementType == doInsert
+}
+
+// IsUpdate 是否 UPDATE 语句
+func (s *Statement) IsUpdate() bool {
+	return s.statementType == doUpdate
+}
+
+// IsDelete 是否 DELETE 语句
+func (s *Statement) IsDelete() bool {
+	return s.statementType == doDelete && len(s.tables) > 0
+}
+
+// Distinct 是否 SELECT DISTINCT
+func (s *Statement) Distinct() bool {
+	return s.distinct
+}
+
+// Selects 返回 SELECT 的列
+func (s *Statement) Selects() []string {
+	return clone(s.selects)
+}
+
+// Tables 返回 FROM, INSERT INTO, UPDATE, DELETE FROM 的表
+func (s *Statement) Tables() []string {
+	return clone(s.tables)
+}
+
+// Joins 返回 JOIN 子句
+func (s *Statement) Joins() []string {
+	return clone(s.join)
+}
+
+// InnerJoins 返回 INNER JOIN 子句
+func (s *Statement) InnerJoins() []string {
+	return clone(s.innerJoin)
+}
+
+// OuterJoins 返回 OUTER JOIN 子句
+func (s *Statement) OuterJoins() []string {
+	return clone(s.outerJoin)
+}
+
+// LeftOuterJoins 返回 LEFT OUTER JOIN 子句
+func (s *Statement) LeftOuterJoins() []string {
+	return clone(s.leftOuterJoin)
+}
+
+// RightOuterJoins 返回 RIGHT OUTER JOIN 子句
+func (s *Statement) RightOuterJoins() []string {
+	return clone(s.rightOuterJoin)
+}
+
+// Where 返回 WHERE 条件, Or(), And() 以 ") OR (", ") AND (" 的形式出现在其中
+func (s *Statement) Where() []string {
+	return clone(s.where)
+}
+
+// Having 返回 HAVING 条件
+func (s *Statement) Having() []string {
+	return clone(s.having)
+}
+
+// GroupBy 返回 GROUP BY 的列
+func (s *Statement) GroupBy() []string {
+	return clone(s.groupBy)
+}
+
+// OrderBy 返回 ORDER BY 的列
+func (s *Statement) OrderBy() []string {
+	return clone(s.orderBy)
+}
+
+// Sets 返回 UPDATE 的 SET 表达式
+func (s *Statement) Sets() []string {
+	return clone(s.sets)
+}
+
+// Columns 返回 INSERT 的列
+func (s *Statement) Columns() []string {
+	return clone(s.columns)
+}
+
+// Values 返回 INSERT 每一行的值
+func (s *Statement) Values() [][]string {
+	values := make([][]string, 0, len(s.values))
+	for _, row := range s.values {
+		if len(row) > 0 {
+			values = append(values, clone(row))
+		}
+	}
+	return values
+}
+
+// Limit 返回 LIMIT 的值
+func (s *Statement) Limit() string {
+	return s.limit
+}
+
+// Offset 返回 OFFSET 的值
+func (s *Statement) Offset() string {
+	return s.offset
+}
+
+func clone(parts []string) []string {
+	if parts == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(parts)), parts...)
+}
+
 func (s *Statement) sql(builder *strings.Builder) {
 
 	switch s.statementType {
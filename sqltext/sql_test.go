@@ -69,3 +69,45 @@ func TestDelete(t *testing.T) {
 	sql.Where("ddf=3")
 	fmt.Println(sql.String())
 }
+
+func TestStatement(t *testing.T) {
+	sql := NewSQL()
+	sql.Select("id", "name")
+	sql.From("user u")
+	sql.LeftOuterJoin("tenant t ON u.tenant_id = t.id")
+	sql.Where("u.id = #{id}")
+	sql.Or()
+	sql.Where("u.name = #{name}")
+	sql.OrderBy("id")
+	sql.Limit("10")
+
+	stmt := sql.Statement()
+	if !stmt.IsSelect() {
+		t.Error("expected select statement")
+	}
+	if got := fmt.Sprint(stmt.Selects()); got != "[id name]" {
+		t.Errorf("selects = %v", got)
+	}
+	if got := fmt.Sprint(stmt.Tables()); got != "[user u]" {
+		t.Errorf("tables = %v", got)
+	}
+	if got := stmt.LeftOuterJoins(); len(got) != 1 {
+		t.Errorf("left outer joins = %v", got)
+	}
+	if got := stmt.Where(); len(got) != 3 || got[1] != or {
+		t.Errorf("where = %v", got)
+	}
+	if stmt.Limit() != "10" || stmt.Offset() != "" {
+		t.Errorf("limit = %v, offset = %v", stmt.Limit(), stmt.Offset())
+	}
+
+	insert := NewSQL()
+	insert.InsertInto("user")
+	insert.IntoColumns("id", "name")
+	insert.IntoValues("1", "'a'")
+	insert.AddRow()
+	insert.IntoValues("2", "'b'")
+	if got := fmt.Sprint(insert.Statement().Values()); got != "[[1 'a'] [2 'b']]" {
+		t.Errorf("values = %v", got)
+	}
+}
@@ -0,0 +1,181 @@
+package trysql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// StmtCacheStats 预处理语句缓存的统计信息
+type StmtCacheStats struct {
+	// Hits 命中缓存的次数
+	Hits uint64
+	// Misses 未命中缓存, 新建预处理语句的次数
+	Misses uint64
+	// Evictions 超出容量被关闭的预处理语句数
+	Evictions uint64
+	// Size 当前缓存的预处理语句数
+	Size int
+}
+
+// stmtCache 以最终 SQL 文本为键的 *sql.Stmt LRU 缓存, 线程安全
+type stmtCache struct {
+	db       *sql.DB
+	capacity int
+
+	mu      sync.Mutex
+	lru     *list.List // *stmtEntry, 最近使用的在前
+	entries map[string]*list.Element
+	stats   StmtCacheStats
+	closed  bool
+}
+
+// stmtEntry 缓存的预处理语句, 正在使用时 refs 大于 0, 被淘汰后等使用结束再关闭
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db *sql.DB, capacity int) *stmtCache {
+	return &stmtCache{db: db, capacity: capacity, lru: list.New(), entries: map[string]*list.Element{}}
+}
+
+// acquire 返回 query 的预处理语句, 使用完毕后必须调用 release
+func (c *stmtCache) acquire(ctx context.Context, query string) (*stmtEntry, error) {
+	if entry := c.lookup(query); entry != nil {
+		return entry, nil
+	}
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Misses++
+	if elem, ok := c.entries[query]; ok {
+		// 其他 goroutine 已经缓存了同样的语句
+		_ = stmt.Close()
+		entry := elem.Value.(*stmtEntry)
+		entry.refs++
+		c.lru.MoveToFront(elem)
+		return entry, nil
+	}
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	if c.closed {
+		entry.evicted = true
+		return entry, nil
+	}
+	c.entries[query] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.capacity {
+		c.evict(c.lru.Back())
+	}
+	return entry, nil
+}
+
+func (c *stmtCache) lookup(query string) *stmtEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[query]
+	if !ok {
+		return nil
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+// evict 从缓存中移除 elem, 没有在使用时关闭预处理语句
+func (c *stmtCache) evict(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*stmtEntry)
+	delete(c.entries, entry.query)
+	c.stats.Evictions++
+	entry.evicted = true
+	if entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	if entry.refs == 0 && entry.evicted {
+		_ = entry.stmt.Close()
+	}
+}
+
+func (c *stmtCache) statistics() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
+}
+
+// close 关闭所有缓存的预处理语句, 之后新建的预处理语句在使用后立即关闭
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		entry := c.lru.Remove(c.lru.Back()).(*stmtEntry)
+		delete(c.entries, entry.query)
+		entry.evicted = true
+		if entry.refs == 0 {
+			_ = entry.stmt.Close()
+		}
+	}
+	c.closed = true
+	return nil
+}
+
+// execUncached 不使用预处理语句缓存执行 SQL, 用于 LOAD DATA 等不支持预处理的语句
+func execUncached(ctx context.Context, dbSession DbSession, query string, args ...any) (sql.Result, error) {
+	switch s := dbSession.(type) {
+	case *NonTxDbSession:
+		return s.DB.ExecContext(ctx, query, args...)
+	case *TxDbSession:
+		return s.DB.ExecContext(ctx, query, args...)
+	default:
+		return dbSession.ExecContext(ctx, query, args...)
+	}
+}
+
+// txStmts 事务中使用的预处理语句, 由缓存的预处理语句通过 tx.StmtContext 绑定到事务, 事务结束时释放
+type txStmts struct {
+	cache   *stmtCache
+	stmts   map[string]*sql.Stmt
+	entries []*stmtEntry
+}
+
+func (ts *txStmts) stmt(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	if stmt, ok := ts.stmts[query]; ok {
+		return stmt, nil
+	}
+	entry, err := ts.cache.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// 事务中的语句可能复用缓存语句的驱动语句, 缓存语句在事务结束前不能关闭
+	ts.entries = append(ts.entries, entry)
+	stmt := tx.StmtContext(ctx, entry.stmt)
+	if ts.stmts == nil {
+		ts.stmts = map[string]*sql.Stmt{}
+	}
+	ts.stmts[query] = stmt
+	return stmt, nil
+}
+
+// release 事务结束后释放使用的缓存语句, 事务中的语句由 sql.Tx 关闭
+func (ts *txStmts) release() {
+	for _, entry := range ts.entries {
+		ts.cache.release(entry)
+	}
+	ts.entries = nil
+	ts.stmts = nil
+}
@@ -0,0 +1,117 @@
+package trysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStmtCache(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false, WithStmtCache(2))
+	for i := 0; i < 3; i++ {
+		if _, err := ssf.NewSqlSession().Update("t").Set("name", "a").Where("id = #{id}", i).DoneRowsAffected(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := ssf.StmtCacheStats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 || fake.prepares != 1 {
+		t.Fatalf("stats = %+v, prepares = %v", stats, fake.prepares)
+	}
+
+	for _, table := range []string{"t1", "t2"} {
+		if _, err := ssf.NewSqlSession().Update(table).Set("name", "a").DoneRowsAffected(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats = ssf.StmtCacheStats()
+	if stats.Evictions != 1 || stats.Size != 2 || fake.stmtCloses != 1 {
+		t.Fatalf("stats = %+v, closes = %v", stats, fake.stmtCloses)
+	}
+
+	if err := ssf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fake.stmtCloses != 3 || ssf.StmtCacheStats().Size != 0 {
+		t.Errorf("closes = %v, stats = %+v", fake.stmtCloses, ssf.StmtCacheStats())
+	}
+}
+
+func TestStmtCacheInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false, WithStmtCache(8))
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		for i := 0; i < 2; i++ {
+			if _, err := session.New().Update("t").Set("name", "a").DoneRowsAffectedContext(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := ssf.StmtCacheStats()
+	if stats.Misses != 1 || stats.Size != 1 || len(fake.executed()) != 2 || fake.commits != 1 {
+		t.Fatalf("stats = %+v, execs = %v, commits = %v", stats, fake.executed(), fake.commits)
+	}
+
+	if _, err := ssf.NewSqlSession().Update("t").Set("name", "a").DoneRowsAffected(); err != nil {
+		t.Fatal(err)
+	}
+	if stats = ssf.StmtCacheStats(); stats.Hits != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestStmtCachePrepareFailure(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.failPrepare = "SELEC "
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false, WithStmtCache(2))
+	var id int64
+	err := ssf.NewSqlSession().AppendRaw("SELEC id FROM t").AsPrimitive(&id)
+	if err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Fatalf("err = %v", err)
+	}
+	// 缓存预处理失败后不使用缓存预处理一次
+	if stats := ssf.StmtCacheStats(); stats.Size != 0 || stats.Misses != 0 || fake.prepares != 2 || len(fake.executed()) != 0 {
+		t.Errorf("stats = %+v, prepares = %v, execs = %v", stats, fake.prepares, fake.executed())
+	}
+
+	err = ssf.DoInTx(func(ctx context.Context, session SqlSession) error {
+		return session.AppendRaw("SELEC id FROM t").AsPrimitiveContext(ctx, &id)
+	})
+	if err == nil || !strings.Contains(err.Error(), "syntax error") || fake.rollbacks != 1 {
+		t.Errorf("err = %v, rollbacks = %v", err, fake.rollbacks)
+	}
+}
+
+func TestStmtCachePrepareFailureExecQuery(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.failPrepare = "bad_col"
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false, WithStmtCache(2))
+	// Exec 和 Query 在缓存预处理失败后同样不使用缓存执行一次, 错误来自该次执行
+	if _, err := ssf.NewSqlSession().Update("t").Set("bad_col", 1).Where("id = #{id}", 1).DoneRowsAffected(); err == nil || fake.prepares != 2 {
+		t.Errorf("err = %v, prepares = %v", err, fake.prepares)
+	}
+	var rows []struct {
+		ID int64 `colname:"id"`
+	}
+	if err := ssf.NewSqlSession().AppendRaw("SELECT id FROM t WHERE bad_col = 1").AsList(&rows); err == nil || fake.prepares != 4 {
+		t.Errorf("err = %v, prepares = %v", err, fake.prepares)
+	}
+
+	err := ssf.DoInTx(func(ctx context.Context, session SqlSession) error {
+		if _, err := session.Update("t").Set("bad_col", 1).Where("id = #{id}", 1).DoneRowsAffectedContext(ctx); err == nil || fake.prepares != 6 {
+			t.Errorf("err = %v, prepares = %v", err, fake.prepares)
+		}
+		return session.AppendRaw("SELECT id FROM t WHERE bad_col = 1").AsListContext(ctx, &rows)
+	})
+	if err == nil || !strings.Contains(err.Error(), "syntax error") || fake.prepares != 8 {
+		t.Errorf("err = %v, prepares = %v", err, fake.prepares)
+	}
+	if stats := ssf.StmtCacheStats(); stats.Size != 0 || len(fake.executed()) != 0 {
+		t.Errorf("stats = %+v, execs = %v", stats, fake.executed())
+	}
+}
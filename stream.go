@@ -0,0 +1,140 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// rowsSession 可以返回 *sql.Rows 的 SqlSession
+type rowsSession interface {
+	queryContext(ctx context.Context) (*sql.Rows, error)
+	scanPlan(typ reflect.Type, columns []string) (*scanPlan, error)
+}
+
+// Rows 逐条读取查询结果的迭代器, T 是 struct
+//
+//	rows, err := Query[Order](ctx, sqlSession.Select("*").From("orders"))
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//	for rows.Next() {
+//		var order Order
+//		if err := rows.Scan(&order); err != nil {
+//			return err
+//		}
+//	}
+//	return rows.Err()
+type Rows[T any] struct {
+	rows *sql.Rows
+	plan *scanPlan
+}
+
+// Query 执行 SQL, 返回逐条读取结果的 Rows, 使用完毕后必须调用 Close
+func Query[T any](ctx context.Context, s SqlSession) (*Rows[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct || isPrimitiveType(typ) {
+		return nil, fmt.Errorf("expected struct, but %v", typ)
+	}
+	rs, ok := s.(rowsSession)
+	if !ok {
+		return nil, fmt.Errorf("unsupported SqlSession %T", s)
+	}
+	rows, err := rs.queryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	plan, err := rs.scanPlan(typ, columns)
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	return &Rows[T]{rows: rows, plan: plan}, nil
+}
+
+// Next 准备读取下一条记录, 没有更多记录或者出错时返回 false
+func (r *Rows[T]) Next() bool {
+	return r.rows.Next()
+}
+
+// Scan 将当前记录映射到 dest
+func (r *Rows[T]) Scan(dest *T) error {
+	var zero T
+	*dest = zero
+	return r.plan.scan(r.rows, reflect.ValueOf(dest).Elem())
+}
+
+// Err 返回迭代过程中的错误
+func (r *Rows[T]) Err() error {
+	return r.rows.Err()
+}
+
+// Close 关闭 Rows, 可以重复调用
+func (r *Rows[T]) Close() error {
+	return r.rows.Close()
+}
+
+// Each 执行 SQL, 逐条将记录映射为 T 后调用 fn, fn 返回错误时停止读取并返回该错误
+func Each[T any](ctx context.Context, s SqlSession, fn func(row *T) error) (err error) {
+	rows, err := Query[T](ctx, s)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	for rows.Next() {
+		row := new(T)
+		if err := rows.Scan(row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachMap 执行 SQL, 逐条将记录映射为 Map 对象后调用 fn, fn 返回错误时停止读取并返回该错误
+func EachMap(ctx context.Context, s SqlSession, fn func(row map[string]any) error) (err error) {
+	rs, ok := s.(rowsSession)
+	if !ok {
+		return fmt.Errorf("unsupported SqlSession %T", s)
+	}
+	rows, err := rs.queryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := rows.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := columnTypeNames(rows)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		row, err := scanMap(rows, columns, types)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,72 @@
+package trysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestEach(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "name"},
+		[]driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"}, []driver.Value{int64(3), "c"})
+	session := NewMySqlSession(NewTxSession(db, false))
+
+	stop := errors.New("stop")
+	var names []string
+	err := Each(context.Background(), session.Select("id", "name").From("user"), func(row *genericUser) error {
+		names = append(names, row.Name)
+		if row.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("err = %v", err)
+	}
+	if len(names) != 2 || names[1] != "b" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestQueryRows(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), nil})
+	session := NewPostgreSqlSession(NewTxSession(db, false))
+
+	rows, err := Query[genericUser](context.Background(), session.Select("id", "name").From("user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var user genericUser
+	var count int
+	for rows.Next() {
+		if err := rows.Scan(&user); err == nil {
+			count++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// NULL 不能映射为 string
+	if count != 1 || user.ID != 2 {
+		t.Errorf("count = %v, user = %v", count, user)
+	}
+}
+
+func TestEachMap(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.addResult([]string{"id", "name"}, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+	session := NewMySqlSession(NewTxSession(db, false))
+
+	var ids []any
+	err := EachMap(context.Background(), session.Select("id", "name").From("user"), func(row map[string]any) error {
+		ids = append(ids, row["id"])
+		return nil
+	})
+	if err != nil || len(ids) != 2 || ids[1] != int64(2) {
+		t.Errorf("ids = %v, err = %v", ids, err)
+	}
+}
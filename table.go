@@ -0,0 +1,90 @@
+package trysql
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table 保留列顺序和数据库类型的查询结果, 用于通用的数据展示
+type Table struct {
+	// Columns 列名
+	Columns []string
+	// Types 列的数据库类型名, 如 VARCHAR, DECIMAL, 驱动不支持时为空字符串
+	Types []string
+	// Rows 每条记录的列值, 与 Columns 一一对应
+	Rows [][]any
+}
+
+// Map 将第 i 条记录生成 Map 对象
+func (t *Table) Map(i int) map[string]any {
+	m := make(map[string]any, len(t.Columns))
+	for j, column := range t.Columns {
+		m[column] = t.Rows[i][j]
+	}
+	return m
+}
+
+// columnTypeNames 返回 rows 每一列的数据库类型名
+func columnTypeNames(rows *sql.Rows) ([]string, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	types := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		types[i] = strings.ToUpper(ct.DatabaseTypeName())
+	}
+	return types, nil
+}
+
+// scanValues 读取 rows 的当前记录, 按照列的数据库类型将 []byte 转换为 string, int64, float64 或 time.Time
+func scanValues(rows *sql.Rows, types []string) ([]any, error) {
+	values := make([]any, len(types))
+	pointers := make([]any, len(types))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		values[i] = convertColumnValue(types[i], value)
+	}
+	return values, nil
+}
+
+// mysqlTimeLayouts MySQL DATE, DATETIME, TIMESTAMP 的文本格式
+var mysqlTimeLayouts = []string{"2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+// convertColumnValue 将驱动返回的 []byte 转换为数据库类型 typeName 对应的 Go 类型,
+// DECIMAL 等精确数值转换为 string 以免丢失精度, 二进制类型和未知类型保持 []byte
+func convertColumnValue(typeName string, value any) any {
+	b, ok := value.([]byte)
+	if !ok || typeName == "" {
+		return value
+	}
+	switch strings.TrimPrefix(typeName, "UNSIGNED ") {
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BIT", "GEOMETRY", "BYTEA":
+		return append([]byte(nil), b...)
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR", "INT2", "INT4", "INT8":
+		if i, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(b), 10, 64); err == nil {
+			return u
+		}
+	case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8":
+		if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+			return f
+		}
+	case "DATE", "DATETIME", "TIMESTAMP":
+		for _, layout := range mysqlTimeLayouts {
+			if t, err := time.Parse(layout, string(b)); err == nil {
+				return t
+			}
+		}
+	}
+	return string(b)
+}
@@ -3,7 +3,9 @@ package trysql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"strconv"
 )
 
 // DbSession (SQL Go database connection) is a wrapper for SQL database handler ( can be *sql.DB or *sql.Tx)
@@ -30,19 +32,40 @@ type DbSession interface {
 // NonTxDbSession is the concrete implementation of DbSession by using *sql.DB
 type NonTxDbSession struct {
 	DB *sql.DB
+	// stmts 不为 nil 时, 使用缓存的预处理语句执行 SQL
+	stmts *stmtCache
 }
 
 // TxDbSession is the concrete implementation of DbSession by using *sql.Tx
 type TxDbSession struct {
 	DB *sql.Tx
+	// stmts 不为 nil 时, 使用绑定到事务的缓存预处理语句执行 SQL
+	stmts *txStmts
+	// depth 正在执行的 InTx 层数, 大于 0 时嵌套的 InTx 使用 SAVEPOINT
+	depth int
+	// savepoints 已创建的 SAVEPOINT 数, 用于生成 SAVEPOINT 名称
+	savepoints int
+	// conn 不为 nil 时, 事务在该连接上开启, 事务结束后关闭
+	conn *sql.Conn
+	// rollbackOnly 加入该事务的 DoInTx 返回错误时设置, 之后只能回滚
+	rollbackOnly bool
 }
 
 func (tx *NonTxDbSession) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return tx.DB.ExecContext(ctx, query, args...)
+	if tx.stmts == nil {
+		return tx.DB.ExecContext(ctx, query, args...)
+	}
+	entry, err := tx.stmts.acquire(ctx, query)
+	if err != nil {
+		// 预处理失败时(如语句不支持预处理)不使用缓存执行一次
+		return tx.DB.ExecContext(ctx, query, args...)
+	}
+	defer tx.stmts.release(entry)
+	return entry.stmt.ExecContext(ctx, args...)
 }
 
 func (tx *NonTxDbSession) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return tx.DB.Exec(query, args...)
+	return tx.ExecContext(context.Background(), query, args...)
 }
 
 func (tx *NonTxDbSession) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
@@ -54,19 +77,38 @@ func (tx *NonTxDbSession) Prepare(query string) (*sql.Stmt, error) {
 }
 
 func (tx *NonTxDbSession) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return tx.DB.QueryContext(ctx, query, args...)
+	if tx.stmts == nil {
+		return tx.DB.QueryContext(ctx, query, args...)
+	}
+	entry, err := tx.stmts.acquire(ctx, query)
+	if err != nil {
+		// 预处理失败时(如语句不支持预处理)不使用缓存执行一次
+		return tx.DB.QueryContext(ctx, query, args...)
+	}
+	// 预处理语句在 Rows 关闭之前被关闭时, database/sql 会推迟到 Rows 关闭后再释放
+	defer tx.stmts.release(entry)
+	return entry.stmt.QueryContext(ctx, args...)
 }
 
 func (tx *NonTxDbSession) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return tx.DB.Query(query, args...)
+	return tx.QueryContext(context.Background(), query, args...)
 }
 
 func (tx *NonTxDbSession) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
-	return tx.DB.QueryRowContext(ctx, query, args...)
+	if tx.stmts == nil {
+		return tx.DB.QueryRowContext(ctx, query, args...)
+	}
+	entry, err := tx.stmts.acquire(ctx, query)
+	if err != nil {
+		// 预处理失败时(如语句不支持预处理)不使用缓存执行一次, 执行的错误由 sql.Row 返回
+		return tx.DB.QueryRowContext(ctx, query, args...)
+	}
+	defer tx.stmts.release(entry)
+	return entry.stmt.QueryRowContext(ctx, args...)
 }
 
 func (tx *NonTxDbSession) QueryRow(query string, args ...interface{}) *sql.Row {
-	return tx.DB.QueryRow(query, args...)
+	return tx.QueryRowContext(context.Background(), query, args...)
 }
 
 // Rollback NOP
@@ -85,11 +127,19 @@ func (tx *NonTxDbSession) InTx(_ func() error) error {
 }
 
 func (tx *TxDbSession) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return tx.DB.ExecContext(ctx, query, args...)
+	if tx.stmts == nil {
+		return tx.DB.ExecContext(ctx, query, args...)
+	}
+	stmt, err := tx.stmts.stmt(ctx, tx.DB, query)
+	if err != nil {
+		// 预处理失败时(如语句不支持预处理)不使用缓存执行一次
+		return tx.DB.ExecContext(ctx, query, args...)
+	}
+	return stmt.ExecContext(ctx, args...)
 }
 
 func (tx *TxDbSession) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return tx.DB.Exec(query, args...)
+	return tx.ExecContext(context.Background(), query, args...)
 }
 
 func (tx *TxDbSession) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
@@ -101,24 +151,55 @@ func (tx *TxDbSession) Prepare(query string) (*sql.Stmt, error) {
 }
 
 func (tx *TxDbSession) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return tx.DB.QueryContext(ctx, query, args...)
+	if tx.stmts == nil {
+		return tx.DB.QueryContext(ctx, query, args...)
+	}
+	stmt, err := tx.stmts.stmt(ctx, tx.DB, query)
+	if err != nil {
+		// 预处理失败时(如语句不支持预处理)不使用缓存执行一次
+		return tx.DB.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
 }
 
 func (tx *TxDbSession) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return tx.DB.Query(query, args...)
+	return tx.QueryContext(context.Background(), query, args...)
 }
 
 func (tx *TxDbSession) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
-	return tx.DB.QueryRowContext(ctx, query, args...)
+	if tx.stmts == nil {
+		return tx.DB.QueryRowContext(ctx, query, args...)
+	}
+	stmt, err := tx.stmts.stmt(ctx, tx.DB, query)
+	if err != nil {
+		// 预处理失败时(如语句不支持预处理)不使用缓存执行一次, 执行的错误由 sql.Row 返回
+		return tx.DB.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
 }
 
 func (tx *TxDbSession) QueryRow(query string, args ...interface{}) *sql.Row {
-	return tx.DB.QueryRow(query, args...)
+	return tx.QueryRowContext(context.Background(), query, args...)
 }
 
+// InTx 在事务中执行 txFunc, 没有错误时提交事务, 否则回滚。
+//
+// 在 txFunc 中再次调用 InTx 时, 使用 SAVEPOINT sp_n 执行嵌套的 txFunc,
+// 没有错误时 RELEASE SAVEPOINT, 否则只回滚到该 SAVEPOINT
 func (tx *TxDbSession) InTx(txFunc func() error) error {
-	var err error
+	return tx.InTxContext(context.Background(), txFunc)
+}
+
+// InTxContext 同 InTx, 嵌套时使用 ctx 执行 SAVEPOINT 相关的语句
+func (tx *TxDbSession) InTxContext(ctx context.Context, txFunc func() error) (err error) {
+	if tx.depth > 0 {
+		return tx.inSavepoint(ctx, txFunc)
+	}
+	tx.depth++
+	defer func() { tx.depth-- }()
+
 	tdb := tx.DB
+	defer tx.release()
 	defer func() {
 		if p := recover(); p != nil {
 			log.Println("found panic and rollback:", p)
@@ -129,24 +210,84 @@ func (tx *TxDbSession) InTx(txFunc func() error) error {
 			panic(p) // re-throw panic after Rollback
 		} else if err != nil {
 			log.Println("found error and rollback:", err)
-			err = tdb.Rollback() // if Rollback returns error update err with commit err
+			if rollbackErr := tdb.Rollback(); rollbackErr != nil {
+				log.Println("rollback failed:", rollbackErr)
+			}
+		} else if tx.rollbackOnly {
+			log.Println("rollback-only and rollback")
+			err = ErrRollbackOnly
+			if rollbackErr := tdb.Rollback(); rollbackErr != nil {
+				log.Println("rollback failed:", rollbackErr)
+			}
 		} else {
 			log.Println("commit")
-			err = tdb.Commit() // if Commit returns error update err with commit err
+			err = tdb.Commit() // 提交失败时返回提交的错误, 如 PostgreSQL 提交时的 serialization failure
+		}
+	}()
+	return txFunc()
+}
+
+// inSavepoint 在 SAVEPOINT 中执行 txFunc, 回滚到 SAVEPOINT 失败时同时返回 txFunc 的错误
+func (tx *TxDbSession) inSavepoint(ctx context.Context, txFunc func() error) (err error) {
+	tx.savepoints++
+	name := savepointName(tx.savepoints)
+	if _, err := tx.DB.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	tx.depth++
+	defer func() {
+		tx.depth--
+		if p := recover(); p != nil {
+			log.Println("found panic and rollback to savepoint:", p)
+			if _, err := tx.DB.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+				panic(err)
+			}
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			log.Println("found error and rollback to savepoint:", err)
+			if _, rollbackErr := tx.DB.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+				err = fmt.Errorf("%w; rollback to savepoint: %v", err, rollbackErr)
+			}
+		} else {
+			_, err = tx.DB.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
 		}
 	}()
-	err = txFunc()
-	return err
+	return txFunc()
+}
+
+// savepointName 返回第 n 个 SAVEPOINT 的名称, 不需要引用, MySQL 和 PostgreSQL 都可以使用
+func savepointName(n int) string {
+	return "sp_" + strconv.Itoa(n)
 }
 
 func (tx *TxDbSession) Rollback() error {
+	defer tx.release()
 	return tx.DB.Rollback()
 }
 
+// Commit 提交事务, 事务被标记为 rollback-only 时回滚并返回 ErrRollbackOnly
 func (tx *TxDbSession) Commit() error {
+	defer tx.release()
+	if tx.rollbackOnly {
+		if err := tx.DB.Rollback(); err != nil {
+			return err
+		}
+		return ErrRollbackOnly
+	}
 	return tx.DB.Commit()
 }
 
+// release 事务结束后释放使用的缓存预处理语句及连接
+func (tx *TxDbSession) release() {
+	if tx.stmts != nil {
+		tx.stmts.release()
+	}
+	if tx.conn != nil {
+		_ = tx.conn.Close()
+		tx.conn = nil
+	}
+}
+
 // NewTxSession  创建 DbSession ,tx 为 true 时， 开启事务
 func NewTxSession(sdb *sql.DB, tx bool) DbSession {
 	return NewTxSessionContext(sdb, tx, context.Background(), nil)
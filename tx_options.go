@@ -0,0 +1,110 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// WithTxOptions 指定 DoInTx 新建事务的隔离级别和是否只读, 加入 ctx 中已经存在的事务时不生效
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(c *txConfig) {
+		c.txOptions = opts
+	}
+}
+
+func (ssf *DefaultSqlSessionFactory) DoInTxWithOptions(ctx context.Context, opts *sql.TxOptions, sqlHandler SqlHandler, txOpts ...TxOption) error {
+	return ssf.DoInTxContext(ctx, sqlHandler, append([]TxOption{WithTxOptions(opts)}, txOpts...)...)
+}
+
+func (ssf *DefaultSqlSessionFactory) DoReadOnly(ctx context.Context, sqlHandler SqlHandler, txOpts ...TxOption) error {
+	return ssf.DoInTxWithOptions(ctx, &sql.TxOptions{ReadOnly: true}, sqlHandler, txOpts...)
+}
+
+func (ssf *DefaultSqlSessionFactory) DoSerializable(ctx context.Context, sqlHandler SqlHandler, txOpts ...TxOption) error {
+	return ssf.DoInTxWithOptions(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, sqlHandler, txOpts...)
+}
+
+// beginTx 开启事务。opts 指定了隔离级别或只读时, 在单独的连接上开启事务:
+// 驱动实现了 driver.ConnBeginTx 时由驱动设置(go-sql-driver/mysql 和 lib/pq 都已实现),
+// 否则使用 SET TRANSACTION 设置, MySQL 在 START TRANSACTION 之前执行, PostgreSQL 在 BEGIN 之后作为事务的第一条语句执行
+func (ssf *DefaultSqlSessionFactory) beginTx(ctx context.Context, opts *sql.TxOptions) (*TxDbSession, error) {
+	if opts == nil || (opts.Isolation == sql.LevelDefault && !opts.ReadOnly) {
+		tx, err := ssf.db.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &TxDbSession{DB: tx}, nil
+	}
+
+	conn, err := ssf.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ssf.beginConnTx(ctx, conn, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &TxDbSession{DB: tx, conn: conn}, nil
+}
+
+// beginConnTx 在 conn 上开启 opts 指定的事务
+func (ssf *DefaultSqlSessionFactory) beginConnTx(ctx context.Context, conn *sql.Conn, opts *sql.TxOptions) (*sql.Tx, error) {
+	var supported bool
+	err := conn.Raw(func(driverConn any) error {
+		_, supported = driverConn.(driver.ConnBeginTx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if supported {
+		return conn.BeginTx(ctx, opts)
+	}
+
+	setSQL, err := setTransactionSQL(opts)
+	if err != nil {
+		return nil, err
+	}
+	if ssf.dbType == Postgresql {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, setSQL); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		return tx, nil
+	}
+	// MySQL 的 SET TRANSACTION 对同一连接的下一个事务生效
+	if _, err := conn.ExecContext(ctx, setSQL); err != nil {
+		return nil, err
+	}
+	return conn.BeginTx(ctx, nil)
+}
+
+// setTransactionSQL 返回设置 opts 的 SET TRANSACTION 语句
+func setTransactionSQL(opts *sql.TxOptions) (string, error) {
+	var modes []string
+	switch opts.Isolation {
+	case sql.LevelDefault:
+	case sql.LevelReadUncommitted:
+		modes = append(modes, "ISOLATION LEVEL READ UNCOMMITTED")
+	case sql.LevelReadCommitted:
+		modes = append(modes, "ISOLATION LEVEL READ COMMITTED")
+	case sql.LevelRepeatableRead:
+		modes = append(modes, "ISOLATION LEVEL REPEATABLE READ")
+	case sql.LevelSerializable:
+		modes = append(modes, "ISOLATION LEVEL SERIALIZABLE")
+	default:
+		return "", fmt.Errorf("unsupported isolation level %v", opts.Isolation)
+	}
+	if opts.ReadOnly {
+		modes = append(modes, "READ ONLY")
+	}
+	return "SET TRANSACTION " + strings.Join(modes, ", "), nil
+}
@@ -0,0 +1,85 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Propagation DoInTx 在 ctx 中已经存在事务时的处理方式, 与 Spring 的事务传播行为一致
+type Propagation uint8
+
+const (
+	// PropagationRequired 加入 ctx 中的事务, 不存在时新建事务, 默认值
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew 总是新建事务, 与 ctx 中的事务相互独立
+	PropagationRequiresNew
+	// PropagationNested 在 ctx 中的事务内使用 SAVEPOINT 执行, 不存在时新建事务
+	PropagationNested
+	// PropagationSupports 加入 ctx 中的事务, 不存在时非事务执行
+	PropagationSupports
+	// PropagationNotSupported 总是非事务执行, 不使用 ctx 中的事务
+	PropagationNotSupported
+	// PropagationMandatory 加入 ctx 中的事务, 不存在时返回 ErrNoTransaction
+	PropagationMandatory
+)
+
+// ErrNoTransaction PropagationMandatory 时 ctx 中没有事务
+var ErrNoTransaction = errors.New("trysql: no existing transaction for propagation mandatory")
+
+// ErrRollbackOnly 加入事务的 DoInTx 返回了错误, 开启事务的 DoInTx 没有返回错误时回滚事务并返回该错误
+var ErrRollbackOnly = errors.New("trysql: transaction rolled back because a joined DoInTx failed")
+
+// TxOption DoInTx 的事务选项
+type TxOption func(*txConfig)
+
+// txConfig DoInTx 使用的事务配置
+type txConfig struct {
+	propagation Propagation
+	retry       *RetryPolicy
+	txOptions   *sql.TxOptions
+}
+
+// WithPropagation 指定 DoInTx 的事务传播行为
+func WithPropagation(propagation Propagation) TxOption {
+	return func(c *txConfig) {
+		c.propagation = propagation
+	}
+}
+
+func newTxConfig(opts []TxOption) txConfig {
+	var c txConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// txContextKey ctx 中保存当前事务的 key
+type txContextKey struct{}
+
+// txContext ctx 中保存的当前事务, 只有同一个 SqlSessionFactory 才能加入
+type txContext struct {
+	ssf       *DefaultSqlSessionFactory
+	dbSession *TxDbSession
+}
+
+// withTx 返回保存 dbSession 为当前事务的 ctx, dbSession 为 nil 时清除 ctx 中的事务
+func (ssf *DefaultSqlSessionFactory) withTx(ctx context.Context, dbSession *TxDbSession) context.Context {
+	if dbSession == nil {
+		if ssf.currentTx(ctx) == nil {
+			return ctx
+		}
+		return context.WithValue(ctx, txContextKey{}, (*txContext)(nil))
+	}
+	return context.WithValue(ctx, txContextKey{}, &txContext{ssf: ssf, dbSession: dbSession})
+}
+
+// currentTx 返回 ctx 中由该 SqlSessionFactory 开启的事务
+func (ssf *DefaultSqlSessionFactory) currentTx(ctx context.Context) *TxDbSession {
+	tc, _ := ctx.Value(txContextKey{}).(*txContext)
+	if tc == nil || tc.ssf != ssf {
+		return nil
+	}
+	return tc.dbSession
+}
@@ -0,0 +1,220 @@
+package trysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNestedInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Postgresql, db, time.Second, false)
+	errInner := errors.New("inner")
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		err := session.InTx(func() error {
+			return session.New().Update("t").Set("name", "a").DoneContext(ctx)
+		})
+		if err != nil {
+			return err
+		}
+		if err := session.InTx(func() error { return errInner }); err != errInner {
+			t.Errorf("err = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var queries []string
+	for _, exec := range fake.executed() {
+		queries = append(queries, exec.query)
+	}
+	expected := []string{"SAVEPOINT sp_1", "UPDATE t\nSET name = $1 ", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_2"}
+	if len(queries) != len(expected) || fake.begins != 1 || fake.commits != 1 || fake.rollbacks != 0 {
+		t.Fatalf("queries = %q, begins = %v, commits = %v", queries, fake.begins, fake.commits)
+	}
+	for i := range expected {
+		if queries[i] != expected[i] {
+			t.Errorf("queries[%d] = %q, expected %q", i, queries[i], expected[i])
+		}
+	}
+}
+
+func TestSavepointName(t *testing.T) {
+	if name := savepointName(3); name != "sp_3" {
+		t.Errorf("name = %v", name)
+	}
+}
+
+func TestNestedInTxWithoutFactory(t *testing.T) {
+	db, fake := newFakeDB()
+	session := NewPostgreSqlSession(NewTxSession(db, true))
+	err := session.InTx(func() error {
+		return session.InTx(func() error { return errors.New("inner") })
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var queries []string
+	for _, exec := range fake.executed() {
+		queries = append(queries, exec.query)
+	}
+	if len(queries) != 2 || queries[0] != "SAVEPOINT sp_1" || queries[1] != "ROLLBACK TO SAVEPOINT sp_1" || fake.rollbacks != 1 {
+		t.Errorf("queries = %q, rollbacks = %v", queries, fake.rollbacks)
+	}
+}
+
+func TestNestedInTxContext(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.failPrepare = "ROLLBACK TO"
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false)
+	errInner := errors.New("inner")
+	nested := WithPropagation(PropagationNested)
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		err := ssf.DoInTxContext(canceled, func(ctx context.Context, session SqlSession) error { return nil }, nested)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v", err)
+		}
+		// 回滚到 SAVEPOINT 失败时同时返回 txFunc 的错误
+		err = ssf.DoInTxContext(ctx, func(ctx context.Context, session SqlSession) error { return errInner }, nested)
+		if !errors.Is(err, errInner) || !strings.Contains(err.Error(), "rollback to savepoint") {
+			t.Errorf("err = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execs := fake.executed(); len(execs) != 1 || execs[0].query != "SAVEPOINT sp_2" {
+		t.Errorf("execs = %v", execs)
+	}
+}
+
+func TestJoinedTxRollbackOnly(t *testing.T) {
+	for _, propagation := range []Propagation{PropagationRequired, PropagationSupports, PropagationMandatory} {
+		db, fake := newFakeDB()
+		ssf := NewSqlSessionFactory(Mysql, db, time.Second, false)
+		err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+			// 外层忽略加入事务的 DoInTx 返回的错误, 事务仍然回滚
+			_ = ssf.DoInTxContext(ctx, func(ctx context.Context, session SqlSession) error {
+				return errors.New("inner")
+			}, WithPropagation(propagation))
+			return nil
+		})
+		if err != ErrRollbackOnly || fake.commits != 0 || fake.rollbacks != 1 {
+			t.Errorf("propagation = %v, err = %v, commits = %v, rollbacks = %v", propagation, err, fake.commits, fake.rollbacks)
+		}
+	}
+
+	db, fake := newFakeDB()
+	tx := NewTxSession(db, true).(*TxDbSession)
+	tx.rollbackOnly = true
+	if err := tx.Commit(); err != ErrRollbackOnly || fake.commits != 0 || fake.rollbacks != 1 {
+		t.Errorf("err = %v, commits = %v, rollbacks = %v", err, fake.commits, fake.rollbacks)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false)
+	update := func(ctx context.Context, session SqlSession) error {
+		return ssf.NewSqlSessionContext(ctx).Update("t").Set("name", "a").DoneContext(ctx)
+	}
+	errInner := errors.New("inner")
+	err := ssf.DoInTxContext(context.Background(), func(ctx context.Context, session SqlSession) error {
+		if _, ok := ssf.NewSqlSessionContext(ctx).(*MySqlSession).dbSession.(*TxDbSession); !ok {
+			t.Error("expected NewSqlSessionContext to join the transaction")
+		}
+		if err := ssf.DoInTxContext(ctx, update); err != nil {
+			return err
+		}
+		if err := ssf.DoInTxContext(ctx, update, WithPropagation(PropagationMandatory)); err != nil {
+			return err
+		}
+		if err := ssf.DoInTxContext(ctx, update, WithPropagation(PropagationRequiresNew)); err != nil {
+			return err
+		}
+		err := ssf.DoInTxContext(ctx, func(ctx context.Context, session SqlSession) error {
+			return errInner
+		}, WithPropagation(PropagationNested))
+		if err != errInner {
+			t.Errorf("err = %v", err)
+		}
+		return ssf.DoInTxContext(ctx, func(ctx context.Context, session SqlSession) error {
+			if _, ok := session.(*MySqlSession).dbSession.(*NonTxDbSession); !ok {
+				t.Error("expected non-transactional session")
+			}
+			return nil
+		}, WithPropagation(PropagationNotSupported))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.begins != 2 || fake.commits != 2 || len(fake.executed()) != 5 {
+		t.Errorf("begins = %v, commits = %v, execs = %v", fake.begins, fake.commits, fake.executed())
+	}
+
+	err = ssf.DoInTx(update, WithPropagation(PropagationMandatory))
+	if err != ErrNoTransaction {
+		t.Errorf("err = %v", err)
+	}
+	if err := ssf.DoInTx(update, WithPropagation(PropagationSupports)); err != nil || fake.begins != 2 {
+		t.Errorf("err = %v, begins = %v", err, fake.begins)
+	}
+}
+
+func TestDoInTxWithOptions(t *testing.T) {
+	noop := func(ctx context.Context, session SqlSession) error { return nil }
+
+	db, fake := newFakeDB()
+	ssf := NewSqlSessionFactory(Mysql, db, time.Second, false)
+	if err := ssf.DoSerializable(context.Background(), noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := ssf.DoReadOnly(context.Background(), noop); err != nil {
+		t.Fatal(err)
+	}
+	execs := fake.executed()
+	if len(execs) != 2 || execs[0].query != "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE" ||
+		execs[1].query != "SET TRANSACTION READ ONLY" || fake.begins != 2 || fake.commits != 2 {
+		t.Errorf("execs = %v, begins = %v, commits = %v", execs, fake.begins, fake.commits)
+	}
+
+	db, fake = newFakeDB()
+	ssf = NewSqlSessionFactory(Postgresql, db, time.Second, false)
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	if err := ssf.DoInTxWithOptions(context.Background(), opts, noop); err != nil {
+		t.Fatal(err)
+	}
+	execs = fake.executed()
+	if len(execs) != 1 || execs[0].query != "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY" || fake.commits != 1 {
+		t.Errorf("execs = %v, commits = %v", execs, fake.commits)
+	}
+
+	opts = &sql.TxOptions{Isolation: sql.LevelLinearizable}
+	if err := ssf.DoInTxWithOptions(context.Background(), opts, noop); err == nil {
+		t.Error("expected error for unsupported isolation level")
+	}
+
+	// 驱动支持 sql.TxOptions 时由 BeginTx 设置, 不执行 SET TRANSACTION
+	db, fake = newFakeDB()
+	fake.txOptions = true
+	ssf = NewSqlSessionFactory(Mysql, db, time.Second, false)
+	if err := ssf.DoSerializable(context.Background(), noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := ssf.DoReadOnly(context.Background(), noop); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.executed()) != 0 || len(fake.beginOpts) != 2 || fake.commits != 2 ||
+		fake.beginOpts[0].Isolation != driver.IsolationLevel(sql.LevelSerializable) || !fake.beginOpts[1].ReadOnly {
+		t.Errorf("execs = %v, beginOpts = %+v", fake.executed(), fake.beginOpts)
+	}
+}